@@ -0,0 +1,51 @@
+package econf
+
+import (
+	"errors"
+)
+
+// ErrAliasCycle defines an error that an alias resolves to itself.
+var ErrAliasCycle = errors.New("alias cycle, maybe an alias registered twice in reverse")
+
+// RegisterAlias registers alias as another name of target with default defaultConfiguration.
+func RegisterAlias(alias, target string) error {
+	return defaultConfiguration.RegisterAlias(alias, target)
+}
+
+// RegisterAlias registers alias as another name of the key target, e.g. an old name kept for compatibility:
+// reads of alias return the value of target, and Set of alias sets target. Aliases may chain.
+// Only the exact key is aliased, not the keys under it. It returns ErrAliasCycle if target resolves to alias.
+func (c *Configuration) RegisterAlias(alias, target string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, ok := target, true; ok; key, ok = c.alias(key) {
+		if key == alias {
+			return &KeyError{Key: alias, Op: "RegisterAlias", Err: ErrAliasCycle}
+		}
+	}
+	c.aliases.Store(alias, target)
+	// invalidate typedCache, keeping the generation odd during a refresh
+	c.generation.Add(2)
+	return nil
+}
+
+// alias returns the target of key if key is an alias.
+func (c *Configuration) alias(key string) (string, bool) {
+	target, ok := c.aliases.Load(key)
+	if !ok {
+		return "", false
+	}
+	return target.(string), true
+}
+
+// canonicalKey resolves the aliases of key.
+// RegisterAlias rejects cycles, so this always terminates.
+func (c *Configuration) canonicalKey(key string) string {
+	for {
+		target, ok := c.alias(key)
+		if !ok {
+			return key
+		}
+		key = target
+	}
+}
@@ -0,0 +1,41 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterAlias(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.address", ":80"))
+	assert.NoError(t, v.RegisterAlias("server.addr", "server.address"))
+	assert.NoError(t, v.RegisterAlias("addr", "server.addr"))
+
+	// reads through alias and target
+	assert.Equal(t, ":80", v.GetString("server.addr"))
+	assert.Equal(t, ":80", v.GetString("addr"))
+	assert.Equal(t, ":80", v.GetString("server.address"))
+	assert.True(t, v.IsSet("addr"))
+
+	// writes through the alias go to the target
+	assert.NoError(t, v.Set("server.addr", ":8080"))
+	assert.Equal(t, ":8080", v.GetString("server.address"))
+	assert.Equal(t, ":8080", v.GetString("addr"))
+	assert.Equal(t, map[string]interface{}{"address": ":8080"}, v.GetStringMap("server"))
+
+	// writes to the target are seen through the alias
+	assert.NoError(t, v.Set("server.address", ":9090"))
+	assert.Equal(t, ":9090", v.GetString("server.addr"))
+}
+
+func TestRegisterAliasCycle(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.RegisterAlias("a", "b"))
+	assert.NoError(t, v.RegisterAlias("b", "c"))
+	assert.ErrorIs(t, v.RegisterAlias("c", "a"), ErrAliasCycle)
+	assert.ErrorIs(t, v.RegisterAlias("d", "d"), ErrAliasCycle)
+
+	assert.NoError(t, v.Set("a", 1))
+	assert.Equal(t, 1, v.GetInt("c"))
+}
@@ -36,6 +36,11 @@ func OnChange(fn func(*Configuration)) {
 	defaultConfiguration.OnChange(fn)
 }
 
+// OnChangeNamed 注册带名称和优先级的change回调函数
+func OnChangeNamed(name string, priority int, fn func(*Configuration)) {
+	defaultConfiguration.OnChangeNamed(name, priority, fn)
+}
+
 // Sub return sub-configuration of defaultConfiguration
 func Sub(key string) *Configuration {
 	return defaultConfiguration.Sub(key)
@@ -48,11 +53,21 @@ func LoadFromDataSource(ds DataSource, unmarshaller Unmarshaller, opts ...Option
 	return defaultConfiguration.LoadFromDataSource(ds, unmarshaller, opts...)
 }
 
+// ReloadNow re-reads the data source of defaultConfiguration immediately.
+func ReloadNow() error {
+	return defaultConfiguration.ReloadNow()
+}
+
 // LoadFromReader loads configuration from provided provider with default defaultConfiguration.
 func LoadFromReader(r io.Reader, unmarshaller Unmarshaller) error {
 	return defaultConfiguration.LoadFromReader(r, unmarshaller)
 }
 
+// LoadJSONStream loads JSON read token by token from r with default defaultConfiguration.
+func LoadJSONStream(r io.Reader) error {
+	return defaultConfiguration.LoadJSONStream(r)
+}
+
 // Apply ...
 func Apply(conf map[string]interface{}) error {
 	return defaultConfiguration.apply(conf)
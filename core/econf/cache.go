@@ -0,0 +1,90 @@
+package econf
+
+import (
+	"reflect"
+)
+
+// typedCacheEntry is the cast value of a key, valid while the generation of its Configuration is unchanged.
+// A key holds one entry, so reading it as another kind replaces the entry.
+type typedCacheEntry struct {
+	generation uint64
+	kind       reflect.Kind
+	value      interface{}
+}
+
+// cachedCast returns the value of key converted by cast. If EnableTypedCache is on, the converted
+// value is cached until the next update of c, so hot paths skip the repeated conversion.
+func cachedCast[T any](c *Configuration, key string, kind reflect.Kind, cast func(interface{}) T) T {
+	// an overlay can't tell when its parent is updated
+	if !defaultContainer.EnableTypedCache || c.parent != nil {
+		return castValue(c.Get(key), cast)
+	}
+	// read the generation before the value, so a value cached during a refresh is never served after it
+	generation := c.generation.Load()
+	// an alias shares the entry of its target
+	canonical := c.canonicalKey(key)
+	if e, ok := c.typedCache.Load(canonical); ok {
+		if entry := e.(*typedCacheEntry); entry.generation == generation && entry.kind == kind {
+			// a hit skips find, which traces the other reads
+			c.traceRead(key)
+			return entry.value.(T)
+		}
+	}
+	value := castValue(c.Get(key), cast)
+	c.typedCache.Store(canonical, &typedCacheEntry{generation: generation, kind: kind, value: value})
+	return value
+}
+
+// castValue returns value as is if it already is a T, skipping cast, else the value converted by cast.
+func castValue[T any](value interface{}, cast func(interface{}) T) T {
+	if v, ok := value.(T); ok {
+		return v
+	}
+	return cast(value)
+}
+
+// mapCacheEntry is the map converted from the subtree source, valid while a key still holds source.
+// Subtrees are never modified once set, as updates copy them, so a replaced subtree has another identity.
+type mapCacheEntry struct {
+	source interface{}
+	value  map[string]string
+}
+
+// cachedStringMapString returns a copy of the map converted from value if it is cached for key.
+func (c *Configuration) cachedStringMapString(key string, value interface{}) (map[string]string, bool) {
+	e, ok := c.mapCache.Load(key)
+	if !ok {
+		return nil, false
+	}
+	entry := e.(*mapCacheEntry)
+	if !sameMap(entry.source, value) {
+		return nil, false
+	}
+	out := make(map[string]string, len(entry.value))
+	for k, v := range entry.value {
+		out[k] = v
+	}
+	return out, true
+}
+
+// cacheStringMapString caches a copy of m converted from the subtree value of key.
+func (c *Configuration) cacheStringMapString(key string, value interface{}, m map[string]string) {
+	if _, ok := value.(map[string]interface{}); !ok {
+		return
+	}
+	cached := make(map[string]string, len(m))
+	for k, v := range m {
+		cached[k] = v
+	}
+	c.mapCache.Store(key, &mapCacheEntry{source: value, value: cached})
+}
+
+// sameMap reports if a and b are the same map[string]interface{}, not just equal ones.
+func sameMap(a, b interface{}) bool {
+	ma, ok := a.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	mb, ok := b.(map[string]interface{})
+	return ok && reflect.ValueOf(ma).UnsafePointer() == reflect.ValueOf(mb).UnsafePointer()
+}
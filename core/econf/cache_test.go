@@ -0,0 +1,169 @@
+package econf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTypedCache(t *testing.T) {
+	withOptions(t, WithTypedCache(true))
+	v := New()
+	assert.NoError(t, v.Set("feature.enabled", "true"))
+	assert.NoError(t, v.Set("feature.limit", "10"))
+
+	assert.True(t, v.GetBool("feature.enabled"))
+	assert.Equal(t, 10, v.GetInt("feature.limit"))
+	e, ok := v.typedCache.Load("feature.limit")
+	assert.True(t, ok)
+	assert.Equal(t, 10, e.(*typedCacheEntry).value)
+	// reading another kind replaces the entry
+	assert.Equal(t, int64(10), v.GetInt64("feature.limit"))
+	assert.Equal(t, float64(10), v.GetFloat64("feature.limit"))
+	assert.Equal(t, "10", v.GetString("feature.limit"))
+	assert.Equal(t, 10, v.GetInt("feature.limit"))
+
+	// Set invalidates
+	assert.NoError(t, v.Set("feature.enabled", false))
+	assert.False(t, v.GetBool("feature.enabled"))
+
+	// Load invalidates
+	assert.NoError(t, v.Load([]byte(`
+[feature]
+limit = "20"
+`), toml.Unmarshal))
+	assert.Equal(t, 20, v.GetInt("feature.limit"))
+	assert.Equal(t, "20", v.GetString("feature.limit"))
+	assert.False(t, v.GetBool("feature.enabled"))
+
+	// RegisterComputed and RegisterAlias invalidate
+	assert.Equal(t, 0, v.GetInt("workers"))
+	assert.NoError(t, v.RegisterComputed("workers", func(*Configuration) interface{} { return 7 }))
+	assert.Equal(t, 7, v.GetInt("workers"))
+	assert.NoError(t, v.Set("server.address", "x"))
+	assert.Equal(t, "", v.GetString("server.addr"))
+	assert.NoError(t, v.RegisterAlias("server.addr", "server.address"))
+	assert.Equal(t, "x", v.GetString("server.addr"))
+	assert.Equal(t, "x", v.GetString("server.address"))
+	// an alias shares the entry of its target
+	assert.NoError(t, v.Set("server.addr", "y"))
+	assert.Equal(t, "y", v.GetString("server.address"))
+	assert.Equal(t, "y", v.GetString("server.addr"))
+}
+
+func TestTypedCacheDisabled(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("feature.limit", 10))
+	assert.Equal(t, 10, v.GetInt("feature.limit"))
+	_, ok := v.typedCache.Load("feature.limit")
+	assert.False(t, ok)
+}
+
+func BenchmarkGetBool(b *testing.B) {
+	for _, enable := range []bool{false, true} {
+		name := "uncached"
+		if enable {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			withOptions(b, WithTypedCache(enable))
+			v := New()
+			_ = v.Set("feature.enabled", "true")
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = v.GetBool("feature.enabled")
+			}
+		})
+	}
+}
+
+// BenchmarkGetTyped reads values already stored with the type of the getter, which skip cast.
+func BenchmarkGetTyped(b *testing.B) {
+	v := New()
+	_ = v.Set("server.host", "localhost")
+	_ = v.Set("server.tls", true)
+	_ = v.Set("server.port", 8080)
+
+	b.Run("GetString", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.GetString("server.host")
+		}
+	})
+	b.Run("GetBool", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.GetBool("server.tls")
+		}
+	})
+	b.Run("GetInt", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.GetInt("server.port")
+		}
+	})
+}
+
+func TestCachedStringMapString(t *testing.T) {
+	withOptions(t, WithTypedCache(true))
+	ds := newFakeDataSource("[labels]\napp = \"ego\"\nenv = \"dev\"\n\n[other]\nname = \"x\"\n")
+	defer ds.Close()
+	v := New()
+	// a sync initial OnChange, so reloaded only receives the reload
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+	reloaded := make(chan struct{}, 1)
+	v.OnChange(func(*Configuration) { reloaded <- struct{}{} })
+
+	expected := map[string]string{"app": "ego", "env": "dev"}
+	assert.Equal(t, expected, v.GetStringMapString("labels"))
+	_, ok := v.mapCache.Load("labels")
+	assert.True(t, ok)
+	// callers get a copy
+	m := v.GetStringMapString("labels")
+	m["app"] = "modified"
+	assert.Equal(t, expected, v.GetStringMapString("labels"))
+
+	// a Set of another subtree keeps the entry, a Set under it replaces it
+	assert.NoError(t, v.Set("other.name", "y"))
+	assert.Equal(t, expected, v.GetStringMapString("labels"))
+	assert.NoError(t, v.Set("labels.env", "prod"))
+	assert.Equal(t, map[string]string{"app": "ego", "env": "prod"}, v.GetStringMapString("labels"))
+
+	ds.update("[labels]\napp = \"ego\"\nregion = \"eu\"\n")
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("no reload")
+	}
+	// reloads merge over the values set before
+	assert.Equal(t, map[string]string{"app": "ego", "env": "prod", "region": "eu"}, v.GetStringMapString("labels"))
+	assert.Equal(t, map[string]string{}, v.GetStringMapString("missing"))
+}
+
+// BenchmarkGetStringMapString reads a large map, converting it on each read or copying the cached one.
+func BenchmarkGetStringMapString(b *testing.B) {
+	labels := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		labels[fmt.Sprintf("label%d", i)] = i
+	}
+	for _, enable := range []bool{false, true} {
+		name := "uncached"
+		if enable {
+			name = "cached"
+		}
+		b.Run(name, func(b *testing.B) {
+			withOptions(b, WithTypedCache(enable))
+			v := New()
+			_ = v.Set("labels", labels)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_ = v.GetStringMapString("labels")
+			}
+		})
+	}
+}
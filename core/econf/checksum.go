@@ -0,0 +1,26 @@
+package econf
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// Checksum returns the checksum of the effective config with default defaultConfiguration.
+func Checksum() string {
+	return defaultConfiguration.Checksum()
+}
+
+// Checksum returns the hex SHA-256 of the effective config encoded as canonical JSON,
+// whose map keys are sorted, so it is stable across runs and map ordering for an identical config.
+// It returns an empty string if the config can't be encoded as JSON.
+func (c *Configuration) Checksum() string {
+	c.mu.RLock()
+	content, err := json.Marshal(normalizeValue(c.override))
+	c.mu.RUnlock()
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,25 @@
+package econf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecksum(t *testing.T) {
+	a := New()
+	assert.NoError(t, a.Load([]byte(`{"name": "app", "server": {"host": "localhost", "port": 80}}`), json.Unmarshal))
+	b := New()
+	assert.NoError(t, b.Load([]byte("[server]\nport = 80\nhost = \"localhost\"\n"), toml.Unmarshal))
+	assert.NoError(t, b.Set("name", "app"))
+
+	assert.Len(t, a.Checksum(), 64)
+	assert.Equal(t, a.Checksum(), b.Checksum())
+	assert.Equal(t, a.Checksum(), a.Clone().Checksum())
+
+	before := b.Checksum()
+	assert.NoError(t, b.Set("server.port", int64(81)))
+	assert.NotEqual(t, before, b.Checksum())
+}
@@ -0,0 +1,57 @@
+package econf
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrKeyShadowed defines an error that a computed key would hide a key set in config.
+var ErrKeyShadowed = errors.New("computed key shadows a key set in config")
+
+// computedKey is a key registered by RegisterComputed with its last computed value.
+type computedKey struct {
+	fn     func(*Configuration) interface{}
+	cached atomic.Pointer[typedCacheEntry]
+}
+
+// RegisterComputed registers a key derived from other keys with default defaultConfiguration.
+func RegisterComputed(key string, fn func(*Configuration) interface{}) error {
+	return defaultConfiguration.RegisterComputed(key, fn)
+}
+
+// RegisterComputed registers key as the value returned by fn, e.g. an effective worker count derived
+// from the configured one and the CPU count. fn runs without holding the lock on the first read of key,
+// and its result is cached until the next update, so it follows reloads.
+// It returns ErrKeyShadowed if key is set in config. The check is only made at registration: a key
+// loaded or set afterwards is shadowed by the computed value. A registered fn replaces the previous one of key.
+func (c *Configuration) RegisterComputed(key string, fn func(*Configuration) interface{}) error {
+	key = c.canonicalKey(key)
+	paths := splitKey(key, c.keyDelim)
+	c.mu.RLock()
+	_, exists, _ := lookupPath(c.override, paths)
+	c.mu.RUnlock()
+	if exists {
+		return &KeyError{Key: key, Op: "RegisterComputed", Err: ErrKeyShadowed}
+	}
+	c.computed.Store(key, &computedKey{fn: fn})
+	// invalidate typedCache, keeping the generation odd during a refresh
+	c.generation.Add(2)
+	return nil
+}
+
+// computedValue returns the value of key if it is a computed key, computing it if the cached one is stale.
+func (c *Configuration) computedValue(key string) (interface{}, bool) {
+	e, ok := c.computed.Load(key)
+	if !ok {
+		return nil, false
+	}
+	ck := e.(*computedKey)
+	// read the generation before computing, so a value computed during a refresh is never served after it
+	generation := c.generation.Load()
+	if entry := ck.cached.Load(); entry != nil && entry.generation == generation {
+		return entry.value, true
+	}
+	value := ck.fn(c)
+	ck.cached.Store(&typedCacheEntry{generation: generation, value: value})
+	return value, true
+}
@@ -0,0 +1,50 @@
+package econf
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterComputed(t *testing.T) {
+	withOptions(t)
+	ds := newFakeDataSource("cpu = 4\nworkers = 8\n")
+	defer ds.Close()
+	v := New()
+	// a sync initial OnChange, so reloaded only receives the reload
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+	reloaded := make(chan struct{}, 1)
+	v.OnChange(func(*Configuration) {
+		reloaded <- struct{}{}
+	})
+
+	calls := 0
+	assert.NoError(t, v.RegisterComputed("effective_workers", func(c *Configuration) interface{} {
+		calls++
+		return min(c.GetInt("cpu"), c.GetInt("workers"))
+	}))
+	assert.Equal(t, 4, v.GetInt("effective_workers"))
+	assert.Equal(t, 4, v.Get("effective_workers"))
+	assert.True(t, v.IsSet("effective_workers"))
+	// cached until the next update
+	assert.Equal(t, 1, calls)
+
+	ds.update("cpu = 4\nworkers = 2\n")
+	select {
+	case <-reloaded:
+	case <-time.After(time.Second):
+		t.Fatal("no reload")
+	}
+	assert.Equal(t, 2, v.GetInt("effective_workers"))
+	assert.Equal(t, 2, calls)
+
+	err := v.RegisterComputed("workers", func(c *Configuration) interface{} { return 1 })
+	assert.ErrorIs(t, err, ErrKeyShadowed)
+	assert.Equal(t, 2, v.GetInt("workers"))
+
+	// a key set after the registration is shadowed
+	assert.NoError(t, v.Set("effective_workers", 16))
+	assert.Equal(t, 2, v.GetInt("effective_workers"))
+}
@@ -1,12 +1,19 @@
 package econf
 
 import (
+	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"os"
 	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mitchellh/mapstructure"
@@ -26,101 +33,410 @@ type Configuration struct {
 	keyDelim  string
 	rawConfig []byte
 	keyMap    *sync.Map
-	onChanges []func(*Configuration)
-
-	watchers map[string][]func(*Configuration)
+	onChanges []changeHandler
+
+	watchers    map[string][]func(*Configuration)
+	keyWatchers map[string][]func(*Configuration)
+	// watchPrefixes indexes the prefixes of watchers
+	watchPrefixes prefixIndex
+	// watchDispatcher runs the callbacks of watchers and keyWatchers
+	watchDispatcher dispatcher
+	// chanWatchers are the watchers registered by WatchChan
+	chanWatchers  map[*chanWatcher]struct{}
+	validators    []func(*Configuration) error
+	activeProfile string
+	secretKeys    map[string]struct{}
+	dataSource    DataSource
+	// dataSourceName is the source name of dataSource
+	dataSourceName string
+	unmarshaller   Unmarshaller
+	// sources holds the name of the source which set each leaf key
+	sources map[string]string
+	// version is increased on every committed update
+	version uint64
+	// lastLoad identifies the content loaded last, see loadFrom
+	lastLoad loadState
+	// metricsHook is notified of loads, see SetMetricsHook
+	metricsHook MetricsHook
+	// disableChangeDetection is DisableChangeDetection as of New or the last LoadFromDataSource
+	disableChangeDetection bool
+	// profileBase is override without the active profile merged, nil while no profile is active
+	profileBase map[string]interface{}
+	// appendedLists holds the lists each layer appended to MergeAppendKeys, by flattened key, see applyFrom
+	appendedLists map[string]map[string][]interface{}
+	// typedCache caches cast values of keys if EnableTypedCache is on
+	typedCache sync.Map
+	// mapCache caches the *mapCacheEntry of GetStringMapString if EnableTypedCache is on
+	mapCache sync.Map
+	// generation is increased whenever keyMap is refreshed, invalidating typedCache.
+	// It is odd while a refresh is in progress.
+	generation atomic.Uint64
+	// snapshot is the state read by find without the lock, once published by an update
+	snapshot atomic.Pointer[snapshot]
+	// fallback supplies the keys missing from override
+	fallback func(key string) (interface{}, bool)
+	logger   Logger
+	// deprecated holds the *deprecation of deprecated keys
+	deprecated sync.Map
+	// aliases holds the target key of alias keys
+	aliases sync.Map
+	// parent is the Configuration an Overlay is layered over
+	parent *Configuration
+	// readStats holds the *atomic.Int64 read count of keys if EnableReadTracing is on
+	readStats sync.Map
+	// lookups tracks the keys cached by find that aren't leaves, if CacheSize is set
+	lookups lruKeys
+	// interned holds the path segments shared by the maps of override if InternKeys is on
+	interned internTable
+	// computed holds the *computedKey of keys registered by RegisterComputed
+	computed sync.Map
+	// defaults holds the values of keys registered by SetDefault
+	defaults sync.Map
+	// paths caches the split paths of keys read by find
+	paths atomic.Pointer[pathCache]
+	// envVars caches the environment variables of EnvOverridePrefix, see envOverrides
+	envVars atomic.Pointer[envSnapshot]
+	// leavesDeferred reports if the leaves of override are missing from keyMap, see refreshInitial
+	leavesDeferred atomic.Bool
 }
 
 const (
 	defaultKeyDelim = "."
+	// secretFileSuffix is appended to a key whose value is the path of a file holding the secret.
+	secretFileSuffix = "_file"
+	// defaultMaxDepth is the default maximum nesting depth flattened by traverse.
+	defaultMaxDepth = 64
 )
 
 // New constructs a new Configuration with provider.
 func New() *Configuration {
 	return &Configuration{
-		override:  make(map[string]interface{}),
-		keyDelim:  defaultKeyDelim,
-		keyMap:    &sync.Map{},
-		onChanges: make([]func(*Configuration), 0),
-		watchers:  make(map[string][]func(*Configuration)),
+		override:    make(map[string]interface{}),
+		keyDelim:    defaultKeyDelim,
+		keyMap:      &sync.Map{},
+		onChanges:   make([]changeHandler, 0),
+		watchers:    make(map[string][]func(*Configuration)),
+		keyWatchers: make(map[string][]func(*Configuration)),
+
+		disableChangeDetection: defaultContainer.DisableChangeDetection,
 	}
 }
 
 // SetKeyDelim set keyDelim of a defaultConfiguration instance.
+// Keys cached with the old delimiter are dropped and the sources of keys are renamed.
+// Keys registered before, e.g. by Watch, RegisterAlias or SetDefault, are not renamed,
+// so it should be called before registering any.
 func (c *Configuration) SetKeyDelim(delim string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	old := c.keyDelim
+	if delim == old {
+		return
+	}
+	c.generation.Add(1)
 	c.keyDelim = delim
+	// keys cached and split with the old delimiter are stale
+	c.paths.Store(nil)
+	if c.keyMap != nil {
+		c.keyMap.Range(func(k, _ interface{}) bool {
+			c.keyMap.Delete(k)
+			return true
+		})
+	}
+	c.mapCache.Range(func(k, _ interface{}) bool {
+		c.mapCache.Delete(k)
+		return true
+	})
+	c.lookups.reset()
+	// the leaves are flattened again with delim on their first need
+	c.leavesDeferred.Store(len(c.override) > 0)
+	sources := make(map[string]string, len(c.sources))
+	for k, source := range c.sources {
+		sources[renameKey(k, old, delim)] = source
+	}
+	c.sources = sources
+	c.generation.Add(1)
 }
 
 // Sub returns new Configuration instance representing a subtree of this instance.
+// The subtree is shared rather than copied, so Sub costs the same for any size of subtree:
+// updates copy the maps they change instead of modifying them, so a Set on either side
+// is never seen by the other.
 func (c *Configuration) Sub(key string) *Configuration {
 	return &Configuration{
 		keyDelim: c.keyDelim,
 		override: c.GetStringMap(key),
 		keyMap:   &sync.Map{},
+
+		disableChangeDetection: c.changeDetectionDisabled(),
+	}
+}
+
+// Clone returns an independent deep copy of this instance.
+// Registered OnChange callbacks and watchers are not copied, registered secret keys and sources are.
+func (c *Configuration) Clone() *Configuration {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	secretKeys := make(map[string]struct{}, len(c.secretKeys))
+	for k := range c.secretKeys {
+		secretKeys[k] = struct{}{}
+	}
+	sources := make(map[string]string, len(c.sources))
+	for k, v := range c.sources {
+		sources[k] = v
+	}
+	return &Configuration{
+		override:      deepCopyMap(c.override),
+		keyDelim:      c.keyDelim,
+		rawConfig:     c.rawConfig,
+		keyMap:        &sync.Map{},
+		onChanges:     make([]changeHandler, 0),
+		watchers:      make(map[string][]func(*Configuration)),
+		keyWatchers:   make(map[string][]func(*Configuration)),
+		activeProfile: c.activeProfile,
+		profileBase:   c.profileBase,
+		secretKeys:    secretKeys,
+		sources:       sources,
+
+		disableChangeDetection: c.disableChangeDetection,
 	}
 }
 
+// changeDetectionDisabled returns disableChangeDetection under the lock.
+func (c *Configuration) changeDetectionDisabled() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.disableChangeDetection
+}
+
 // WriteConfig ...
 func (c *Configuration) WriteConfig() error {
 	// return c.provider.Write(c.override)
 	return nil
 }
 
+// changeHandler is a callback registered by OnChange or OnChangeNamed.
+type changeHandler struct {
+	name     string
+	priority int
+	fn       func(*Configuration)
+}
+
 // OnChange register a callback when configuration change emit.
 func (c *Configuration) OnChange(fn func(*Configuration)) {
+	c.OnChangeNamed("", 0, fn)
+}
+
+// OnChangeNamed register a named callback when configuration change emit.
+// Callbacks run one by one in descending priority, callbacks with the same priority
+// run in registration order. Registering a non-empty name again replaces the previous callback.
+func (c *Configuration) OnChangeNamed(name string, priority int, fn func(*Configuration)) {
 	c.mu.Lock()
-	c.onChanges = append(c.onChanges, fn)
-	c.mu.Unlock()
+	defer c.mu.Unlock()
+	if name != "" {
+		for i, handler := range c.onChanges {
+			if handler.name == name {
+				c.onChanges = append(c.onChanges[:i], c.onChanges[i+1:]...)
+				break
+			}
+		}
+	}
+	c.onChanges = append(c.onChanges, changeHandler{name: name, priority: priority, fn: fn})
+	sort.SliceStable(c.onChanges, func(i, j int) bool {
+		return c.onChanges[i].priority > c.onChanges[j].priority
+	})
+}
+
+// fireOnChanges runs the OnChange callbacks in order.
+// The callbacks are snapshotted so they run without holding the lock.
+func (c *Configuration) fireOnChanges() {
+	c.mu.RLock()
+	if c.disableChangeDetection {
+		c.mu.RUnlock()
+		return
+	}
+	handlers := make([]changeHandler, len(c.onChanges))
+	copy(handlers, c.onChanges)
+	c.mu.RUnlock()
+	for _, handler := range handlers {
+		handler.fn(c)
+	}
 }
 
 // LoadFromDataSource ...
+// The loaded layer is named by WithSourceName, for SourceOf.
 func (c *Configuration) LoadFromDataSource(ds DataSource, unmarshaller Unmarshaller, opts ...Option) error {
+	// the source name only applies to this data source
+	var local Container
 	for _, opt := range opts {
 		opt(&defaultContainer)
+		opt(&local)
 	}
+	defaultContainer.SourceName = ""
+	source := local.SourceName
 
 	content, err := ds.ReadConfig()
 	if err != nil {
 		return fmt.Errorf("LoadFromDataSource ReadConfig, err: %w", err)
 	}
 
-	if err := c.Load(content, unmarshaller); err != nil {
+	if _, err := c.loadFrom(source, dataSourceLayer(source, ds), content, unmarshaller); err != nil {
 		return fmt.Errorf("LoadFromDataSource Load, err: %w", err)
 	}
+	c.mu.Lock()
+	c.dataSource = ds
+	c.dataSourceName = source
+	c.unmarshaller = unmarshaller
+	// snapshot the option, the watching goroutine must not read defaultContainer
+	c.disableChangeDetection = defaultContainer.DisableChangeDetection
+	c.mu.Unlock()
 
+	// 首次加载配置执行 OnChange
+	syncOnChange := defaultContainer.SyncOnChange
+	if syncOnChange {
+		c.fireOnChanges()
+	}
 	go func() {
-		// 首次加载配置执行 OnChange
-		c.mu.RLock()
-		for _, change := range c.onChanges {
-			change(c)
+		if !syncOnChange {
+			c.fireOnChanges()
 		}
-		c.mu.RUnlock()
 
 		for range ds.IsConfigChanged() {
-			if content, err := ds.ReadConfig(); err == nil {
-				_ = c.Load(content, unmarshaller)
-				c.mu.RLock()
-				for _, change := range c.onChanges {
-					change(c)
-				}
-				c.mu.RUnlock()
-			}
+			_ = c.reload(source, ds, unmarshaller)
 		}
 	}()
 
 	return nil
 }
 
+// ReloadNow re-reads and applies the DataSource of the last LoadFromDataSource immediately,
+// then fires the OnChange callbacks, without waiting for the DataSource to report a change.
+// Content identical to the one last applied is skipped, see SetMetricsHook.
+func (c *Configuration) ReloadNow() error {
+	c.mu.RLock()
+	source, ds, unmarshaller := c.dataSourceName, c.dataSource, c.unmarshaller
+	c.mu.RUnlock()
+	if ds == nil {
+		return ErrNoDataSource
+	}
+	return c.reload(source, ds, unmarshaller)
+}
+
+// reload re-reads ds and fires the OnChange callbacks if it was applied.
+func (c *Configuration) reload(source string, ds DataSource, unmarshaller Unmarshaller) error {
+	content, err := ds.ReadConfig()
+	if err != nil {
+		return fmt.Errorf("reload ReadConfig, err: %w", err)
+	}
+	noop, err := c.loadFrom(source, dataSourceLayer(source, ds), content, unmarshaller)
+	if err != nil {
+		return fmt.Errorf("reload Load, err: %w", err)
+	}
+	if !noop {
+		c.fireOnChanges()
+	}
+	return nil
+}
+
 // Load ...
 func (c *Configuration) Load(content []byte, unmarshal Unmarshaller) error {
-	c.rawConfig = content
-	configuration := make(map[string]interface{})
-	if err := unmarshal(content, &configuration); err != nil {
+	_, err := c.loadFrom("", "", content, unmarshal)
+	return err
+}
+
+// loadFrom loads content as the layer of the named source, identified by layer, see applyFrom.
+// If content is identical to the last one loaded from source and nothing was updated since,
+// e.g. as re-read by a polling DataSource, it is neither parsed nor applied, and noop is reported.
+func (c *Configuration) loadFrom(source, layer string, content []byte, unmarshal Unmarshaller) (noop bool, err error) {
+	sum := sha256.Sum256(content)
+	if c.loadUnchanged(source, sum) {
+		return true, nil
+	}
+	configuration, err := c.parse(content, unmarshal)
+	if err != nil {
+		return false, err
+	}
+	return false, c.loadParsed(source, layer, sum, content, configuration)
+}
+
+// loadUnchanged reports if the content summed as sum is the last one loaded from source and nothing
+// was updated since, notifying the metrics hook of the noop load if so.
+func (c *Configuration) loadUnchanged(source string, sum [sha256.Size]byte) bool {
+	c.mu.RLock()
+	noop := c.lastLoad == loadState{source: source, sum: sum, version: c.version}
+	c.mu.RUnlock()
+	if noop {
+		c.observeLoad(source, true)
+	}
+	return noop
+}
+
+// loadParsed applies configuration, parsed from content summed as sum, as the layer of the named source.
+// It checks the required env, keeps content as RawConfig, resolves the active profile and records
+// the load for loadUnchanged.
+func (c *Configuration) loadParsed(source, layer string, sum [sha256.Size]byte, content []byte, configuration map[string]interface{}) error {
+	if err := RequireEnv(c.requiredEnv(configuration)...); err != nil {
 		return err
 	}
+	if defaultContainer.DiscardRawConfig {
+		c.rawConfig = nil
+	} else {
+		c.rawConfig = content
+	}
+	profile, err := c.profileOf(configuration)
+	if err != nil {
+		return err
+	}
+	version, err := c.applyFrom(source, layer, configuration, profile)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	c.lastLoad = loadState{source: source, sum: sum, version: version}
+	c.mu.Unlock()
+	c.observeLoad(source, false)
+	return nil
+}
+
+// LoadUnderPrefix loads content nested under prefix, e.g. prefix `vendor` loads `a.b` as `vendor.a.b`.
+// Refs in content are resolved relative to content itself, and profiles are not resolved.
+// It does not replace RawConfig.
+func (c *Configuration) LoadUnderPrefix(prefix string, content []byte, unmarshal Unmarshaller) error {
+	if prefix == "" {
+		return c.Load(content, unmarshal)
+	}
+	configuration, err := c.parse(content, unmarshal)
+	if err != nil {
+		return err
+	}
+	paths := splitKey(prefix, c.keyDelim)
+	for i := len(paths) - 1; i >= 0; i-- {
+		configuration = map[string]interface{}{paths[i]: configuration}
+	}
 	return c.apply(configuration)
 }
 
+// parse unmarshals content and resolves refs if enabled.
+func (c *Configuration) parse(content []byte, unmarshal Unmarshaller) (map[string]interface{}, error) {
+	configuration := make(map[string]interface{})
+	if err := unmarshal(content, &configuration); err != nil {
+		return nil, err
+	}
+	if err := c.prepare(configuration); err != nil {
+		return nil, err
+	}
+	return configuration, nil
+}
+
+// prepare interns the keys and strings of a decoded configuration and resolves refs if enabled.
+func (c *Configuration) prepare(configuration map[string]interface{}) error {
+	c.internTree(configuration)
+	if defaultContainer.EnableRefs {
+		return resolveRefs(configuration, c.keyDelim)
+	}
+	return nil
+}
+
 // LoadFromReader loads configuration from provided data source.
 func (c *Configuration) LoadFromReader(reader io.Reader, unmarshaller Unmarshaller) error {
 	content, err := io.ReadAll(reader)
@@ -131,55 +447,245 @@ func (c *Configuration) LoadFromReader(reader io.Reader, unmarshaller Unmarshall
 }
 
 func (c *Configuration) apply(conf map[string]interface{}) error {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	_, err := c.applyFrom("", "", conf, nil)
+	return err
+}
 
-	var changes = make(map[string]interface{})
+// applyFrom merges conf as the layer of the named source and returns the version it committed.
+// A non-nil profile is merged over the result, see commit.
+// A non-empty layer identifies the layer across reloads: the lists it appends to MergeAppendKeys
+// replace the ones it appended before, instead of being appended again.
+func (c *Configuration) applyFrom(source, layer string, conf map[string]interface{}, profile *profileLayer) (uint64, error) {
+	m := merger{container: defaultContainer, sep: c.keyDelim}
+	if layer != "" && len(m.container.MergeAppendKeys) > 0 {
+		c.mu.RLock()
+		m.appended = c.appendedLists[layer]
+		c.mu.RUnlock()
+		m.appending = make(map[string][]interface{})
+	}
+	version, err := c.commit(source, profile, func(override map[string]interface{}) (map[string]interface{}, []string) {
+		// only the maps changed by conf are copied, unchanged subtrees are shared with override
+		candidate, _ := m.mergeCopy("", override, conf)
+		return candidate, c.wonLeaves(candidate, conf)
+	})
+	if err == nil && m.appending != nil {
+		c.mu.Lock()
+		if c.appendedLists == nil {
+			c.appendedLists = make(map[string]map[string][]interface{})
+		}
+		c.appendedLists[layer] = m.appending
+		c.mu.Unlock()
+	}
+	return version, err
+}
+
+// dataSourceLayer returns the layer identifying the loads of ds named source, see applyFrom.
+func dataSourceLayer(source string, ds DataSource) string {
+	if source != "" {
+		return source
+	}
+	if rv := reflect.ValueOf(ds); rv.Kind() == reflect.Ptr {
+		return fmt.Sprintf("%T@%x", ds, rv.Pointer())
+	}
+	return fmt.Sprintf("%T", ds)
+}
+
+// update applies mutate to a copy of the override map, commits it and notifies the changed keys.
+// The leaf keys returned by mutate are recorded as set by source.
+func (c *Configuration) update(source string, mutate func(override map[string]interface{}) []string) error {
+	_, err := c.commit(source, nil, func(override map[string]interface{}) (map[string]interface{}, []string) {
+		candidate := deepCopyMap(override)
+		return candidate, mutate(candidate)
+	})
+	return err
+}
+
+// commit commits the override map built by build from the current one, which it must not modify,
+// and notifies the changed keys. The leaf keys returned by build are recorded as set by source.
+// It returns the version of the committed override.
+// Maps already handed out by getters are never mutated, so callers may read them without the lock.
+// No callback runs while the lock is held, so OnChange callbacks, watchers and validators
+// may call Set or any getter without deadlocking.
+// If validators are registered, the candidate is validated without the lock, and committed only
+// if all validators pass and no other update was committed meanwhile.
+// While a profile is active, build is applied to profileBase too. A non-nil profile is merged over
+// the base built, instead of over override, so the values of a previously active profile are dropped,
+// and it becomes the active profile once committed.
+func (c *Configuration) commit(source string, profile *profileLayer, build func(override map[string]interface{}) (map[string]interface{}, []string)) (uint64, error) {
+	for {
+		c.mu.RLock()
+		version := c.version
+		validators := c.validators
+		override := c.override
+		base := c.profileBase
+		c.mu.RUnlock()
+
+		var candidate, newBase map[string]interface{}
+		var set []string
+		switch {
+		case profile != nil:
+			if base == nil {
+				// the first profile activated, override is the base
+				base = override
+			}
+			newBase, set = build(base)
+			m := merger{container: defaultContainer, sep: c.keyDelim}
+			candidate, _ = m.mergeCopy("", newBase, profile.values)
+			set = append(set, c.wonLeaves(candidate, profile.values)...)
+		case base != nil:
+			candidate, set = build(override)
+			newBase, _ = build(base)
+		default:
+			candidate, set = build(override)
+		}
+		if len(validators) > 0 {
+			if err := c.validate(candidate, validators); err != nil {
+				return 0, err
+			}
+		}
 
-	xmap.MergeStringMap(c.override, conf)
-	for k, v := range c.traverse(c.keyDelim) {
-		orig, ok := c.keyMap.Load(k)
-		if ok && !reflect.DeepEqual(orig, v) {
-			changes[k] = v
+		c.mu.Lock()
+		if c.version != version {
+			// committed by another update meanwhile, retry on the new state
+			c.mu.Unlock()
+			continue
+		}
+		// the leaves of the old override are needed to detect changes
+		c.flattenLeaves()
+		initial := len(c.override) == 0
+		c.override = candidate
+		c.profileBase = newBase
+		if profile != nil {
+			c.activeProfile = profile.name
+		}
+		c.version++
+		if initial && c.refreshInitial() {
+			c.recordSources(source, set, nil)
+		} else {
+			c.recordSources(source, set, c.refresh())
+		}
+		version = c.version
+		c.mu.Unlock()
+		return version, nil
+	}
+}
+
+// refresh updates keyMap from override and notifies the changed keys, with lock held.
+// Cached subtrees and misses are dropped, as they may be stale now. It returns the leaves of override.
+func (c *Configuration) refresh() map[string]interface{} {
+	detect := !c.disableChangeDetection
+	var changes = make(map[string]interface{})
+	c.generation.Add(1)
+	c.publish()
+
+	leaves := c.traverse(c.keyDelim)
+	for k, v := range leaves {
+		if detect {
+			orig, ok := c.keyMap.Load(k)
+			if ok && !reflect.DeepEqual(orig, v) {
+				changes[k] = v
+			}
 		}
 		c.keyMap.Store(k, v)
 	}
+	c.dropLookups(leaves)
+	c.generation.Add(1)
 
 	if len(changes) > 0 {
 		c.notifyChanges(changes)
 	}
+	return leaves
+}
 
-	return nil
+// dropLookups removes the cached subtrees and misses from keyMap, keeping the leaves, with lock held.
+func (c *Configuration) dropLookups(leaves map[string]interface{}) {
+	c.keyMap.Range(func(k, _ interface{}) bool {
+		if _, ok := leaves[k.(string)]; !ok {
+			c.keyMap.Delete(k)
+		}
+		return true
+	})
+	c.lookups.reset()
 }
 
 func (c *Configuration) notifyChanges(changes map[string]interface{}) {
 	var changedWatchPrefixMap = map[string]struct{}{}
 
-	for watchPrefix := range c.watchers {
-		for key := range changes {
-			// 前缀匹配即可
-			// todo 可能产生错误匹配
-			if strings.HasPrefix(key, watchPrefix) {
-				changedWatchPrefixMap[watchPrefix] = struct{}{}
-			}
-		}
+	for key := range changes {
+		// 前缀匹配即可
+		// todo 可能产生错误匹配
+		c.watchPrefixes.match(key, func(watchPrefix string) {
+			changedWatchPrefixMap[watchPrefix] = struct{}{}
+		})
 	}
 
 	for changedWatchPrefix := range changedWatchPrefixMap {
 		for _, handle := range c.watchers[changedWatchPrefix] {
-			go handle(c)
+			handle := handle
+			c.watchDispatcher.dispatch(func() { handle(c) })
+		}
+	}
+
+	for key := range changes {
+		for _, handle := range c.keyWatchers[key] {
+			handle := handle
+			c.watchDispatcher.dispatch(func() { handle(c) })
 		}
 	}
+
+	for w := range c.chanWatchers {
+		w.notify(changes, c.keyDelim)
+	}
 }
 
-// Set ...
+// Set sets config value for key.
+// A delimiter escaped by a backslash is kept within its segment, e.g. `hosts.a\.b` sets the leaf `a.b` under `hosts`.
+// It returns an error and leaves config untouched if a registered validator rejects the new value,
+// or ErrMalformedKey if key has an empty segment. Setting an alias sets its target.
 func (c *Configuration) Set(key string, val interface{}) error {
-	paths := strings.Split(key, c.keyDelim)
+	key = c.canonicalKey(key)
+	paths := splitKey(key, c.keyDelim)
+	for _, path := range paths {
+		if path == "" {
+			return &KeyError{Key: key, Op: "Set", Err: ErrMalformedKey}
+		}
+	}
+	if len(paths) < maxDepth() {
+		return c.setAt(paths, val)
+	}
+	// an ancestor of key is flattened as a leaf, refresh it all
 	lastKey := paths[len(paths)-1]
-	m := deepSearch(c.override, paths[:len(paths)-1])
-	m[lastKey] = val
-	return c.apply(m)
-	// c.keyMap.Store(key, val)
+	return c.update(sourceSet, func(override map[string]interface{}) []string {
+		m := deepSearch(override, paths[:len(paths)-1])
+		m[lastKey] = val
+		return c.leavesOf(key, val)
+	})
+}
+
+// deepCopyMap returns a deep copy of m, so that merging it never aliases the source.
+func deepCopyMap(m map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = deepCopyValue(v)
+	}
+	return out
+}
+
+func deepCopyValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		return deepCopyMap(vv)
+	case map[interface{}]interface{}:
+		return deepCopyMap(xmap.ToMapStringInterface(vv))
+	case []interface{}:
+		out := make([]interface{}, len(vv))
+		for i, e := range vv {
+			out[i] = deepCopyValue(e)
+		}
+		return out
+	default:
+		return v
+	}
 }
 
 func deepSearch(m map[string]interface{}, path []string) map[string]interface{} {
@@ -206,14 +712,86 @@ func (c *Configuration) Get(key string) interface{} {
 	return c.find(key)
 }
 
+// SafeGet returns the value associated with the key with default defaultConfiguration, recovering from panics.
+func SafeGet(key string) (interface{}, error) {
+	return defaultConfiguration.SafeGet(key)
+}
+
+// SafeGet returns the value associated with the key like Get, but returns any panic
+// raised while resolving the key as an error, e.g. for admin endpoints echoing arbitrary keys.
+func (c *Configuration) SafeGet(key string) (value interface{}, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			value = nil
+			err = fmt.Errorf("%s, get panic: %v", key, r)
+		}
+	}()
+	return c.Get(key), nil
+}
+
+// IsSet checks if the key has a value with default defaultConfiguration.
+func IsSet(key string) bool {
+	return defaultConfiguration.IsSet(key)
+}
+
+// IsSet checks if the key has a value. A key explicitly set to null is not set,
+// nor is a key set to an empty string if WithTreatEmptyAsUnset is enabled.
+func (c *Configuration) IsSet(key string) bool {
+	value := c.Get(key)
+	if str, ok := value.(string); ok && str == "" {
+		return !defaultContainer.TreatEmptyAsUnset
+	}
+	return value != nil
+}
+
 // GetString returns the value associated with the key as a string with default defaultConfiguration.
 func GetString(key string) string {
 	return defaultConfiguration.GetString(key)
 }
 
 // GetString returns the value associated with the key as a string.
+// `${VAR}` tokens are expanded from the environment if WithEnvExpansion is enabled.
 func (c *Configuration) GetString(key string) string {
-	return cast.ToString(c.Get(key))
+	return c.expandEnvOf(key, cachedCast(c, key, reflect.String, cast.ToString))
+}
+
+// GetStringE returns the value associated with the key as a string, or an error if missing, with default defaultConfiguration.
+func GetStringE(key string) (string, error) {
+	return defaultConfiguration.GetStringE(key)
+}
+
+// GetStringE returns the value associated with the key as a string, for required config.
+// A missing or null key returns ErrInvalidKey, while a key explicitly set to "" returns "" and no error.
+// A value that can't be converted to a string, e.g. a map, returns the conversion error.
+func (c *Configuration) GetStringE(key string) (string, error) {
+	value := c.Get(key)
+	if value == nil {
+		return "", &KeyError{Key: key, Op: "GetStringE", Err: ErrInvalidKey}
+	}
+	str, err := cast.ToStringE(value)
+	if err != nil {
+		return "", &KeyError{Key: key, Op: "GetStringE", Err: err}
+	}
+	return c.expandEnvOf(key, str), nil
+}
+
+// GetStringChain returns the value of key, else of the environment variable envVar, else def, with default defaultConfiguration.
+func GetStringChain(key, envVar, def string) string {
+	return defaultConfiguration.GetStringChain(key, envVar, def)
+}
+
+// GetStringChain returns the value associated with the key as a string if the key is set,
+// else the value of the environment variable envVar if not empty, else def.
+func (c *Configuration) GetStringChain(key, envVar, def string) string {
+	if c.IsSet(key) {
+		return c.GetString(key)
+	}
+	if envVar != "" {
+		if val := os.Getenv(envVar); val != "" {
+			return val
+		}
+	}
+	return def
 }
 
 // GetBool returns the value associated with the key as a boolean with default defaultConfiguration.
@@ -223,7 +801,24 @@ func GetBool(key string) bool {
 
 // GetBool returns the value associated with the key as a boolean.
 func (c *Configuration) GetBool(key string) bool {
-	return cast.ToBool(c.Get(key))
+	return cachedCast(c, key, reflect.Bool, cast.ToBool)
+}
+
+// GetBoolTruthy returns the value associated with the key as a boolean with default defaultConfiguration.
+func GetBoolTruthy(key string) bool {
+	return defaultConfiguration.GetBoolTruthy(key)
+}
+
+// GetBoolTruthy returns the value associated with the key as a boolean.
+// Besides what GetBool accepts, a string value may be one of the case-insensitive
+// tokens yes/no, on/off, enabled/disabled, y/n, 1/0 or true/false.
+func (c *Configuration) GetBoolTruthy(key string) bool {
+	value := c.Get(key)
+	if str, ok := value.(string); ok {
+		b, _ := parseTruthy(str)
+		return b
+	}
+	return cast.ToBool(value)
 }
 
 // GetInt returns the value associated with the key as an integer with default defaultConfiguration.
@@ -233,7 +828,78 @@ func GetInt(key string) int {
 
 // GetInt returns the value associated with the key as an integer.
 func (c *Configuration) GetInt(key string) int {
-	return cast.ToInt(c.Get(key))
+	return cachedCast(c, key, reflect.Int, cast.ToInt)
+}
+
+// GetIntStrict returns the value associated with the key as an integer, or an error, with default defaultConfiguration.
+func GetIntStrict(key string) (int, error) {
+	return defaultConfiguration.GetIntStrict(key)
+}
+
+// GetIntStrict returns the value associated with the key as an integer.
+// Unlike GetInt, which silently truncates, it returns ErrIntOverflow if the value doesn't fit an int,
+// e.g. beyond 32 bits on 32-bit platforms, and ErrNotInteger if it is a float with a fraction, e.g. 3.7.
+// A missing key returns ErrInvalidKey.
+func (c *Configuration) GetIntStrict(key string) (int, error) {
+	value := c.Get(key)
+	if value == nil {
+		return 0, &KeyError{Key: key, Op: "GetIntStrict", Err: ErrInvalidKey}
+	}
+	i, err := toIntStrict(value, strconv.IntSize)
+	if err != nil {
+		return 0, &KeyError{Key: key, Op: "GetIntStrict", Err: err}
+	}
+	return int(i), nil
+}
+
+// ErrIntOverflow defines an error that an integer value doesn't fit the target type.
+var ErrIntOverflow = errors.New("integer overflow")
+
+// ErrNotInteger defines an error that a number has a fractional part, so it isn't an integer.
+var ErrNotInteger = errors.New("not an integer, maybe a fraction")
+
+// toIntStrict casts value to an integer of the given bit size, returning ErrIntOverflow if it doesn't fit.
+// A float, e.g. a number decoded from JSON, returns ErrNotInteger if it isn't integral.
+func toIntStrict(value interface{}, bits int) (int64, error) {
+	maxInt := int64(1)<<(bits-1) - 1
+	switch v := value.(type) {
+	case float32:
+		return floatToIntStrict(float64(v), bits)
+	case float64:
+		return floatToIntStrict(v, bits)
+	case uint, uint64, uint32, uintptr:
+		u, err := cast.ToUint64E(value)
+		if err != nil {
+			return 0, err
+		}
+		if u > uint64(maxInt) {
+			return 0, fmt.Errorf("%w: %d exceeds %d bits", ErrIntOverflow, u, bits)
+		}
+		return int64(u), nil
+	}
+	i, err := cast.ToInt64E(value)
+	if err != nil {
+		return 0, err
+	}
+	if i > maxInt || i < -maxInt-1 {
+		return 0, fmt.Errorf("%w: %d exceeds %d bits", ErrIntOverflow, i, bits)
+	}
+	return i, nil
+}
+
+// floatToIntStrict converts f to an integer of the given bit size, unlike cast which truncates
+// fractions and wraps values beyond int64.
+func floatToIntStrict(f float64, bits int) (int64, error) {
+	if math.Trunc(f) != f {
+		// NaN too
+		return 0, fmt.Errorf("%w: %v", ErrNotInteger, f)
+	}
+	// -2^(bits-1) and 2^(bits-1) are exact floats, unlike the max int64
+	limit := math.Ldexp(1, bits-1)
+	if f < -limit || f >= limit {
+		return 0, fmt.Errorf("%w: %v exceeds %d bits", ErrIntOverflow, f, bits)
+	}
+	return int64(f), nil
 }
 
 // GetInt64 returns the value associated with the key as an integer with default defaultConfiguration.
@@ -243,7 +909,40 @@ func GetInt64(key string) int64 {
 
 // GetInt64 returns the value associated with the key as an integer.
 func (c *Configuration) GetInt64(key string) int64 {
-	return cast.ToInt64(c.Get(key))
+	return cachedCast(c, key, reflect.Int64, cast.ToInt64)
+}
+
+// GetInt64Radix returns the value associated with the key as an integer in the given base with default defaultConfiguration.
+func GetInt64Radix(key string, base int) int64 {
+	return defaultConfiguration.GetInt64Radix(key, base)
+}
+
+// GetInt64Radix returns the value associated with the key as an integer.
+// A string value is parsed in the given base by strconv.ParseInt, other values are cast as GetInt64 does.
+// It returns 0 if the value can't be parsed.
+func (c *Configuration) GetInt64Radix(key string, base int) int64 {
+	value := c.Get(key)
+	str, ok := value.(string)
+	if !ok {
+		return cast.ToInt64(value)
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(str), base, 64)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+// GetIntAuto returns the value associated with the key as an integer with default defaultConfiguration.
+func GetIntAuto(key string) int {
+	return defaultConfiguration.GetIntAuto(key)
+}
+
+// GetIntAuto returns the value associated with the key as an integer.
+// The base of a string value is detected from its prefix: 0x for hex, 0o or 0 for octal
+// (e.g. a file mode "0644"), 0b for binary, otherwise decimal.
+func (c *Configuration) GetIntAuto(key string) int {
+	return int(c.GetInt64Radix(key, 0))
 }
 
 // GetFloat64 returns the value associated with the key as a float64 with default defaultConfiguration.
@@ -253,7 +952,52 @@ func GetFloat64(key string) float64 {
 
 // GetFloat64 returns the value associated with the key as a float64.
 func (c *Configuration) GetFloat64(key string) float64 {
-	return cast.ToFloat64(c.Get(key))
+	return cachedCast(c, key, reflect.Float64, toLocaleFloat64)
+}
+
+// GetFloat64D returns the value associated with the key as a float64, or def if not set, with default defaultConfiguration.
+func GetFloat64D(key string, def float64) float64 {
+	return defaultConfiguration.GetFloat64D(key, def)
+}
+
+// GetFloat64D returns the value associated with the key as a float64, or def if the key is not set.
+func (c *Configuration) GetFloat64D(key string, def float64) float64 {
+	if !c.IsSet(key) {
+		return def
+	}
+	return c.GetFloat64(key)
+}
+
+// GetPercent returns the value associated with the key as a fraction with default defaultConfiguration.
+func GetPercent(key string) float64 {
+	return defaultConfiguration.GetPercent(key)
+}
+
+// GetPercent returns the value associated with the key as a float64, a string with a trailing `%`
+// being parsed into a fraction, e.g. "10%" gives 0.1. It returns 0 if the value can't be parsed.
+func (c *Configuration) GetPercent(key string) float64 {
+	value := c.Get(key)
+	if str, ok := value.(string); ok {
+		if f, ok := parsePercent(normalizeDecimal(str, defaultContainer.DecimalSeparator)); ok {
+			return f
+		}
+	}
+	return toLocaleFloat64(value)
+}
+
+// GetFloat32 returns the value associated with the key as a float32 with default defaultConfiguration.
+func GetFloat32(key string) float32 {
+	return defaultConfiguration.GetFloat32(key)
+}
+
+// GetFloat32 returns the value associated with the key as a float32.
+// Values are narrowed from float64, so precision beyond about 7 significant digits is lost.
+func (c *Configuration) GetFloat32(key string) float32 {
+	value := c.Get(key)
+	if f, ok := value.(float32); ok {
+		return f
+	}
+	return float32(toLocaleFloat64(value))
 }
 
 // GetTime returns the value associated with the key as time with default defaultConfiguration.
@@ -266,14 +1010,73 @@ func (c *Configuration) GetTime(key string) time.Time {
 	return cast.ToTime(c.Get(key))
 }
 
+// GetTimeUnix returns the value associated with the key, as Unix seconds, as a time with default defaultConfiguration.
+func GetTimeUnix(key string) time.Time {
+	return defaultConfiguration.GetTimeUnix(key)
+}
+
+// GetTimeUnix returns the value associated with the key, a number or numeric string of Unix seconds, as a time.
+// It returns the zero time if the key is missing or not an integer.
+func (c *Configuration) GetTimeUnix(key string) time.Time {
+	sec, ok := c.getEpoch(key)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// GetTimeUnixMilli returns the value associated with the key, as Unix milliseconds, as a time with default defaultConfiguration.
+func GetTimeUnixMilli(key string) time.Time {
+	return defaultConfiguration.GetTimeUnixMilli(key)
+}
+
+// GetTimeUnixMilli returns the value associated with the key, a number or numeric string of Unix milliseconds, as a time.
+// It returns the zero time if the key is missing or not an integer.
+func (c *Configuration) GetTimeUnixMilli(key string) time.Time {
+	msec, ok := c.getEpoch(key)
+	if !ok {
+		return time.Time{}
+	}
+	return time.UnixMilli(msec)
+}
+
+func (c *Configuration) getEpoch(key string) (int64, bool) {
+	value := c.Get(key)
+	if value == nil {
+		return 0, false
+	}
+	epoch, err := cast.ToInt64E(value)
+	return epoch, err == nil
+}
+
 // GetDuration returns the value associated with the key as a duration with default defaultConfiguration.
 func GetDuration(key string) time.Duration {
 	return defaultConfiguration.GetDuration(key)
 }
 
 // GetDuration returns the value associated with the key as a duration.
+// Besides what cast accepts, such as "30s", the value may be an object of units,
+// e.g. {seconds: 30, millis: 500}, see durationUnits.
 func (c *Configuration) GetDuration(key string) time.Duration {
-	return cast.ToDuration(c.Get(key))
+	value := c.Get(key)
+	if d, ok := durationFromMap(value); ok {
+		return d
+	}
+	return cast.ToDuration(value)
+}
+
+// GetDurationWithDefault returns the value associated with the key as a duration, or def if not set, with default defaultConfiguration.
+func GetDurationWithDefault(key string, def time.Duration) time.Duration {
+	return defaultConfiguration.GetDurationWithDefault(key, def)
+}
+
+// GetDurationWithDefault returns the value associated with the key as a duration, or def if the key is not set.
+// Unlike GetDuration, an explicit "0s" is returned as 0 rather than def.
+func (c *Configuration) GetDurationWithDefault(key string, def time.Duration) time.Duration {
+	if !c.IsSet(key) {
+		return def
+	}
+	return c.GetDuration(key)
 }
 
 // GetStringSlice returns the value associated with the key as a slice of strings with default defaultConfiguration.
@@ -282,8 +1085,45 @@ func GetStringSlice(key string) []string {
 }
 
 // GetStringSlice returns the value associated with the key as a slice of strings.
+// A map whose keys are all indexes, e.g. `{0: a, 1: b}`, is read as the list of its values ordered by index.
+// Elements are trimmed and empty ones dropped if WithCleanStringSlices is enabled.
 func (c *Configuration) GetStringSlice(key string) []string {
-	return cast.ToStringSlice(c.Get(key))
+	if defaultContainer.CleanStringSlices {
+		return c.GetStringSliceClean(key)
+	}
+	return c.getStringSlice(key)
+}
+
+// GetStringSliceClean returns the value associated with the key as a slice of strings,
+// with whitespace trimmed and empty elements dropped, with default defaultConfiguration.
+func GetStringSliceClean(key string) []string {
+	return defaultConfiguration.GetStringSliceClean(key)
+}
+
+// GetStringSliceClean returns the value associated with the key as a slice of strings,
+// with whitespace trimmed and empty elements dropped.
+func (c *Configuration) GetStringSliceClean(key string) []string {
+	raw := c.getStringSlice(key)
+	out := make([]string, 0, len(raw))
+	for _, str := range raw {
+		if str = strings.TrimSpace(str); str != "" {
+			out = append(out, str)
+		}
+	}
+	return out
+}
+
+func (c *Configuration) getStringSlice(key string) []string {
+	value := c.Get(key)
+	if defaultContainer.EnableCSVSlices {
+		if str, ok := value.(string); ok {
+			return c.expandEnvSliceOf(key, splitCSV(str))
+		}
+	}
+	if s, ok := indexedSlice(value); ok {
+		value = s
+	}
+	return c.expandEnvSliceOf(key, cast.ToStringSlice(value))
 }
 
 // GetSlice returns the value associated with the key as a slice of strings with default defaultConfiguration.
@@ -302,8 +1142,104 @@ func GetStringMap(key string) map[string]interface{} {
 }
 
 // GetStringMap returns the value associated with the key as a map of interfaces.
+// It never returns nil: a missing or non-map key yields an empty map.
 func (c *Configuration) GetStringMap(key string) map[string]interface{} {
-	return cast.ToStringMap(c.Get(key))
+	m := cast.ToStringMap(c.Get(key))
+	if m == nil {
+		return map[string]interface{}{}
+	}
+	return m
+}
+
+// GetStringMapInterface is an alias of GetStringMap with default defaultConfiguration.
+func GetStringMapInterface(key string) map[string]interface{} {
+	return defaultConfiguration.GetStringMapInterface(key)
+}
+
+// GetStringMapInterface is an alias of GetStringMap.
+func (c *Configuration) GetStringMapInterface(key string) map[string]interface{} {
+	return c.GetStringMap(key)
+}
+
+// GetStringMapKeys returns the sorted child keys of the map at key with default defaultConfiguration.
+func GetStringMapKeys(key string) []string {
+	return defaultConfiguration.GetStringMapKeys(key)
+}
+
+// GetStringMapKeys returns the sorted child keys of the map at key,
+// or an empty slice if the key is missing or not a map.
+func (c *Configuration) GetStringMapKeys(key string) []string {
+	m, err := cast.ToStringMapE(c.Get(key))
+	if err != nil {
+		return []string{}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetStringMapMerged returns defaults deep merged with the map at key with default defaultConfiguration.
+func GetStringMapMerged(key string, defaults map[string]interface{}) map[string]interface{} {
+	return defaultConfiguration.GetStringMapMerged(key, defaults)
+}
+
+// GetStringMapMerged returns defaults deep merged with the map at key: nested maps are merged
+// recursively and any other config value replaces the default. The result is a copy, so neither
+// defaults nor the config are mutated through it.
+func (c *Configuration) GetStringMapMerged(key string, defaults map[string]interface{}) map[string]interface{} {
+	out := deepCopyMap(defaults)
+	overlayMap(out, deepCopyMap(c.GetStringMap(key)))
+	return out
+}
+
+// overlayMap deep merges src into dest, src winning except where both sides are maps.
+func overlayMap(dest, src map[string]interface{}) {
+	for k, sv := range src {
+		if sm, ok := sv.(map[string]interface{}); ok {
+			if dm, ok := dest[k].(map[string]interface{}); ok {
+				overlayMap(dm, sm)
+				continue
+			}
+		}
+		dest[k] = sv
+	}
+}
+
+// GetNested rebuilds the nested map under prefix from the flattened keys with default defaultConfiguration.
+func GetNested(prefix string) map[string]interface{} {
+	return defaultConfiguration.GetNested(prefix)
+}
+
+// GetNested rebuilds the nested map under prefix from the flattened keys, e.g. `a.b=1` and `a.c=2`
+// under prefix `a` give {b: 1, c: 2}. An empty prefix rebuilds the whole config.
+// Only the loaded and set keys are used, not the values of RegisterFallback or SetDefault.
+// The keys of an Overlay are layered over those of its parent.
+func (c *Configuration) GetNested(prefix string) map[string]interface{} {
+	out := make(map[string]interface{})
+	if c.parent != nil {
+		out = c.parent.GetNested(prefix)
+	}
+	c.mu.RLock()
+	leaves := c.traverse(c.keyDelim)
+	c.mu.RUnlock()
+	if prefix != "" {
+		prefix += c.keyDelim
+	}
+	for key, v := range leaves {
+		if v == nil || !strings.HasPrefix(key, prefix) {
+			continue
+		}
+		// a subtree below WithMaxDepth is kept whole, copied since readers share override
+		if m, ok := v.(map[string]interface{}); ok {
+			v = deepCopyMap(m)
+		}
+		paths := splitKey(strings.TrimPrefix(key, prefix), c.keyDelim)
+		deepSearch(out, paths[:len(paths)-1])[paths[len(paths)-1]] = v
+	}
+	return out
 }
 
 // GetStringMapString returns the value associated with the key as a map of strings with default defaultConfiguration.
@@ -312,8 +1248,89 @@ func GetStringMapString(key string) map[string]string {
 }
 
 // GetStringMapString returns the value associated with the key as a map of strings.
+// `${VAR}` tokens in the values are expanded from the environment if WithEnvExpansion is enabled.
+// The map is a copy the caller may modify, even when it is cached.
 func (c *Configuration) GetStringMapString(key string) map[string]string {
-	return cast.ToStringMapString(c.Get(key))
+	value := c.Get(key)
+	// expanded values depend on the environment at the time of the read
+	cacheable := defaultContainer.EnableTypedCache && !defaultContainer.EnableEnvExpansion && c.parent == nil
+	if cacheable {
+		if m, ok := c.cachedStringMapString(key, value); ok {
+			return m
+		}
+	}
+	m := cast.ToStringMapString(value)
+	for k, v := range m {
+		m[k] = c.expandEnvOf(joinKey(key, k, c.keyDelim), v)
+	}
+	if cacheable {
+		c.cacheStringMapString(key, value, m)
+	}
+	return m
+}
+
+// GetStringMapStringNormalized returns the value associated with the key as a map of strings
+// with normalized keys, with default defaultConfiguration.
+func GetStringMapStringNormalized(key string) map[string]string {
+	return defaultConfiguration.GetStringMapStringNormalized(key)
+}
+
+// GetStringMapStringNormalized returns the value associated with the key as a map of strings like GetStringMapString,
+// with its keys trimmed and lowercased, e.g. for headers looked up case-insensitively.
+// Of keys normalized alike, such as `Accept` and `accept`, the value of the first in sorted order is kept.
+func (c *Configuration) GetStringMapStringNormalized(key string) map[string]string {
+	m := c.GetStringMapString(key)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make(map[string]string, len(m))
+	for _, k := range keys {
+		nk := strings.ToLower(strings.TrimSpace(k))
+		if _, ok := out[nk]; !ok {
+			out[nk] = m[k]
+		}
+	}
+	return out
+}
+
+// ErrUnknownKey defines an error that a subtree contains keys not allowed.
+var ErrUnknownKey = errors.New("unknown key, maybe a typo in config")
+
+// AssertKnownKeys checks the child keys of the map at key with default defaultConfiguration.
+func AssertKnownKeys(key string, allowed []string) error {
+	return defaultConfiguration.AssertKnownKeys(key, allowed)
+}
+
+// AssertKnownKeys returns an error listing the child keys of the map at key which are not in allowed,
+// which catches config typos without decoding into a struct.
+// A missing key is not an error, since there is nothing unexpected in it,
+// while a key whose value is not a map is.
+func (c *Configuration) AssertKnownKeys(key string, allowed []string) error {
+	value := c.Get(key)
+	if value == nil {
+		return nil
+	}
+	m, err := cast.ToStringMapE(value)
+	if err != nil {
+		return fmt.Errorf("%s is not a map, err: %w", key, err)
+	}
+	known := make(map[string]struct{}, len(allowed))
+	for _, k := range allowed {
+		known[k] = struct{}{}
+	}
+	var unknown []string
+	for k := range m {
+		if _, ok := known[k]; !ok {
+			unknown = append(unknown, k)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	return fmt.Errorf("%s has %s, err: %w", key, strings.Join(unknown, ","), ErrUnknownKey)
 }
 
 // GetSliceStringMap returns the value associated with the slice of maps.
@@ -327,8 +1344,64 @@ func GetStringMapStringSlice(key string) map[string][]string {
 }
 
 // GetStringMapStringSlice returns the value associated with the key as a map to a slice of strings.
+// A scalar value becomes a single-element slice, or is split on commas if WithCSVSlices is enabled.
+// `${VAR}` tokens are expanded after splitting if WithEnvExpansion is enabled,
+// so an environment variable containing commas stays a single element.
+// Duplicate elements are dropped, keeping the first, if WithDedupStringSlices is enabled.
+// Maps of indexed keys, e.g. from flattened `rules.get.0` and `rules.get.1`, become slices
+// ordered by index if WithIndexedSlices is enabled.
+// The map and its slices are copies the caller may modify.
 func (c *Configuration) GetStringMapStringSlice(key string) map[string][]string {
-	return cast.ToStringMapStringSlice(c.Get(key))
+	value := c.Get(key)
+	if defaultContainer.IndexedSlices {
+		value = indexedChildren(value)
+	}
+	// cast returns a map[string][]string value as is, and []string elements of other maps too
+	m := cast.ToStringMapStringSlice(value)
+	out := make(map[string][]string, len(m))
+	for k, v := range m {
+		out[k] = append(make([]string, 0, len(v)), v...)
+	}
+	if defaultContainer.EnableCSVSlices {
+		for k, v := range cast.ToStringMap(value) {
+			if str, ok := v.(string); ok {
+				out[k] = splitCSV(str)
+			}
+		}
+	}
+	for k, v := range out {
+		v = c.expandEnvSliceOf(joinKey(key, k, c.keyDelim), v)
+		if defaultContainer.DedupStringSlices {
+			v = dedupStrings(v)
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// GetSecret returns the secret associated with the key with default defaultConfiguration.
+func GetSecret(key string) (string, error) {
+	return defaultConfiguration.GetSecret(key)
+}
+
+// GetSecret returns the value associated with the key as a string.
+// If the key is not set, it reads the file referenced by key+"_file" instead,
+// which suits secrets mounted as files, e.g. `password_file: /run/secrets/db`.
+// A trailing newline in the file content is trimmed.
+func (c *Configuration) GetSecret(key string) (string, error) {
+	if value := c.Get(key); value != nil {
+		return cast.ToString(value), nil
+	}
+	fileKey := key + secretFileSuffix
+	path := c.GetString(fileKey)
+	if path == "" {
+		return "", &KeyError{Key: key, Op: "GetSecret", Err: ErrInvalidKey}
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("%s read secret file, err: %w", fileKey, err)
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
 }
 
 // UnmarshalWithExpect unmarshal key, returns expect if failed
@@ -353,6 +1426,9 @@ func UnmarshalKey(key string, rawVal interface{}, opts ...Option) error {
 // ErrInvalidKey ...
 var ErrInvalidKey = errors.New("invalid key, maybe not exist in config")
 
+// ErrNoDataSource defines an error that config was not loaded from a DataSource.
+var ErrNoDataSource = errors.New("no data source, maybe not loaded by LoadFromDataSource")
+
 // UnmarshalKey takes a single key and unmarshal it into a Struct.
 func (c *Configuration) UnmarshalKey(key string, rawVal interface{}, opts ...Option) error {
 	var options = defaultContainer
@@ -361,11 +1437,13 @@ func (c *Configuration) UnmarshalKey(key string, rawVal interface{}, opts ...Opt
 	}
 
 	config := mapstructure.DecoderConfig{
-		DecodeHook:       mapstructure.StringToTimeDurationHookFunc(),
+		DecodeHook:       cachedDecodeHook(options),
 		Result:           rawVal,
 		TagName:          options.TagName,
 		WeaklyTypedInput: options.WeaklyTypedInput,
 		Squash:           options.Squash,
+		ZeroFields:       options.ZeroFields,
+		Metadata:         options.Metadata,
 	}
 	decoder, err := mapstructure.NewDecoder(&config)
 	if err != nil {
@@ -374,40 +1452,209 @@ func (c *Configuration) UnmarshalKey(key string, rawVal interface{}, opts ...Opt
 	if key == "" {
 		c.mu.RLock()
 		defer c.mu.RUnlock()
-		return decoder.Decode(c.override)
+		zeroFields(rawVal, options)
+		if err := decoder.Decode(c.override); err != nil {
+			return &KeyError{Key: key, Op: "UnmarshalKey", Err: err}
+		}
+		return nil
 	}
 
 	value := c.Get(key)
 	if value == nil {
-		return fmt.Errorf(key+",err: %w", ErrInvalidKey)
+		return &KeyError{Key: key, Op: "UnmarshalKey", Err: ErrInvalidKey}
+	}
+
+	zeroFields(rawVal, options)
+	if err := decoder.Decode(value); err != nil {
+		return &KeyError{Key: key, Op: "UnmarshalKey", Err: err}
 	}
+	return nil
+}
 
-	return decoder.Decode(value)
+// UnmarshalKeys decodes each key into the pointer it maps to with default defaultConfiguration.
+func UnmarshalKeys(targets map[string]interface{}, opts ...Option) error {
+	return defaultConfiguration.UnmarshalKeys(targets, opts...)
+}
+
+// UnmarshalKeys decodes each key into the pointer it maps to, as UnmarshalKey does.
+// It decodes every key even if some fail, and returns the errors of all failing keys joined in key order.
+// With WithDecodeWorkers, keys are decoded in parallel, so the targets must not share memory.
+func (c *Configuration) UnmarshalKeys(targets map[string]interface{}, opts ...Option) error {
+	var options = defaultContainer
+	for _, opt := range opts {
+		opt(&options)
+	}
+	keys := make([]string, 0, len(targets))
+	for key := range targets {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// each key has its own slot, so workers never share an error
+	errs := make([]error, len(keys))
+	workers := options.DecodeWorkers
+	if workers <= 1 {
+		for i, key := range keys {
+			errs[i] = c.UnmarshalKey(key, targets[key], opts...)
+		}
+		return errors.Join(errs...)
+	}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers && w < len(keys); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				errs[i] = c.UnmarshalKey(keys[i], targets[keys[i]], opts...)
+			}
+		}()
+	}
+	for i := range keys {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// zeroFields resets the value rawVal points to, since mapstructure only empties maps with ZeroFields,
+// leaving struct fields absent from config untouched.
+func zeroFields(rawVal interface{}, options Container) {
+	if !options.ZeroFields {
+		return
+	}
+	rv := reflect.ValueOf(rawVal).Elem()
+	rv.Set(reflect.Zero(rv.Type()))
+}
+
+// DecodeSlice decodes the list of objects associated with the key into a slice of T,
+// using the same decoder options as UnmarshalKey.
+// A missing key is not an error: it returns nil, nil, so optional lists need no special casing.
+func DecodeSlice[T any](c *Configuration, key string, opts ...Option) ([]T, error) {
+	if c.Get(key) == nil {
+		return nil, nil
+	}
+	var out []T
+	if err := c.UnmarshalKey(key, &out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RawSubtrees returns each child of the map at key encoded as JSON with default defaultConfiguration.
+func RawSubtrees(key string) (map[string]json.RawMessage, error) {
+	return defaultConfiguration.RawSubtrees(key)
+}
+
+// RawSubtrees returns each child of the map at key encoded as JSON, e.g. for every plugin under
+// `plugins` to decode its own config lazily. It returns ErrInvalidKey if key is missing.
+func (c *Configuration) RawSubtrees(key string) (map[string]json.RawMessage, error) {
+	value := c.Get(key)
+	if value == nil {
+		return nil, &KeyError{Key: key, Op: "RawSubtrees", Err: ErrInvalidKey}
+	}
+	m, err := cast.ToStringMapE(value)
+	if err != nil {
+		return nil, &KeyError{Key: key, Op: "RawSubtrees", Err: err}
+	}
+	out := make(map[string]json.RawMessage, len(m))
+	for k, v := range m {
+		raw, err := json.Marshal(deepCopyValue(v))
+		if err != nil {
+			return nil, &KeyError{Key: joinKey(key, k, c.keyDelim), Op: "RawSubtrees", Err: err}
+		}
+		out[k] = raw
+	}
+	return out, nil
 }
 
 func (c *Configuration) find(key string) interface{} {
-	dd, ok := c.keyMap.Load(key)
-	if ok {
-		return dd
+	return c.resolve(key, c.findInTree)
+}
+
+// resolve resolves key as find does, with inTree resolving the canonical key from the config tree.
+func (c *Configuration) resolve(key string, inTree func(key string) interface{}) interface{} {
+	c.traceRead(key)
+	c.warnDeprecated(key)
+	key = c.canonicalKey(key)
+	if val, ok := c.computedValue(key); ok {
+		return val
+	}
+	envPrefix := defaultContainer.EnvOverridePrefix
+	if envPrefix != "" {
+		if val, ok := c.envOverrides(envPrefix)[envName(envPrefix, key, c.keyDelim)]; ok {
+			return val
+		}
+	}
+	var dd interface{}
+	if c.parent != nil {
+		dd = c.findOverlay(key)
+	} else {
+		dd = inTree(key)
+	}
+	// an empty string is unset too with TreatEmptyAsUnset
+	if dd == nil || (defaultContainer.TreatEmptyAsUnset && dd == "") {
+		if val, ok := c.defaultValue(key); ok {
+			dd = val
+		}
+	}
+	if envPrefix != "" {
+		return c.mergeEnvOverrides(envPrefix, key, dd)
 	}
+	return dd
+}
 
-	paths := strings.Split(key, c.keyDelim)
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	m := xmap.DeepSearchInMap(c.override, paths[:len(paths)-1]...)
-	dd = m[paths[len(paths)-1]]
-	c.keyMap.Store(key, dd)
+// findInTree resolves key from the cache, then from override, then from the fallback.
+func (c *Configuration) findInTree(key string) interface{} {
+	disableCache := defaultContainer.DisableCache
+	if !disableCache {
+		dd, ok := c.keyMap.Load(key)
+		if ok {
+			if defaultContainer.CacheSize > 0 {
+				c.lookups.touch(key)
+			}
+			return dd
+		}
+	}
+
+	paths := c.splitPath(key)
+	// read the generation before the state, so a value read during a refresh is never cached after it
+	generation := c.generation.Load()
+	var (
+		override map[string]interface{}
+		fallback func(key string) (interface{}, bool)
+	)
+	if s := c.snapshot.Load(); s != nil {
+		override, fallback = s.override, s.fallback
+	} else {
+		c.mu.RLock()
+		override, fallback = c.override, c.fallback
+		defer c.mu.RUnlock()
+	}
+	// lookupPath never modifies override, which readers share
+	dd, ok, _ := lookupPath(override, paths)
+	if !ok && fallback != nil {
+		dd, _ = fallback(key)
+	}
+	// skip caching during a refresh, since it may have set key
+	if !disableCache && generation%2 == 0 {
+		c.cacheLookup(key, dd, generation)
+	}
 	return dd
 }
 
-func lookup(prefix string, target map[string]interface{}, data map[string]interface{}, sep string) {
+// lookup flattens target into data. Maps nested deeper than depth levels are not
+// flattened further but stored as a leaf, so pathological nesting can't blow the stack.
+func lookup(prefix string, target map[string]interface{}, data map[string]interface{}, sep string, depth int) {
 	for k, v := range target {
-		pp := fmt.Sprintf("%s%s%s", prefix, sep, k)
-		if prefix == "" {
-			pp = k
+		pp := joinKey(prefix, k, sep)
+		if depth <= 1 {
+			data[pp] = v
+			continue
 		}
 		if dd, err := cast.ToStringMapE(v); err == nil {
-			lookup(pp, dd, data, sep)
+			lookup(pp, dd, data, sep, depth-1)
 		} else {
 			data[pp] = v
 		}
@@ -416,10 +1663,18 @@ func lookup(prefix string, target map[string]interface{}, data map[string]interf
 
 func (c *Configuration) traverse(sep string) map[string]interface{} {
 	data := make(map[string]interface{})
-	lookup("", c.override, data, sep)
+	lookup("", c.override, data, sep, maxDepth())
 	return data
 }
 
+// maxDepth returns the maximum nesting depth flattened by traverse.
+func maxDepth() int {
+	if defaultContainer.MaxDepth > 0 {
+		return defaultContainer.MaxDepth
+	}
+	return defaultMaxDepth
+}
+
 func (c *Configuration) raw() []byte {
 	return c.rawConfig
 }
@@ -42,7 +42,7 @@ func (m *mockDataSource) IsConfigChanged() <-chan struct{} {
 			tempC.mu.RLock()
 			defer tempC.mu.RUnlock()
 			for _, change := range tempC.onChanges {
-				change(tempC)
+				change.fn(tempC)
 			}
 			close(changed)
 		}
@@ -180,3 +180,108 @@ func newWithSymlinkedConfigFile(t *testing.T) (*Configuration, string, string, f
 	assert.Equal(t, "bar", v.Get("foo"))
 	return v, watchDir, configFile, cleanup, wg
 }
+
+// fakeDataSource is a DataSource whose content is updated by tests.
+type fakeDataSource struct {
+	mu      sync.Mutex
+	content []byte
+	changed chan struct{}
+}
+
+func newFakeDataSource(content string) *fakeDataSource {
+	return &fakeDataSource{content: []byte(content), changed: make(chan struct{})}
+}
+
+func (f *fakeDataSource) Parse(string, bool) ConfigType {
+	return ConfigTypeToml
+}
+
+func (f *fakeDataSource) ReadConfig() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.content, nil
+}
+
+func (f *fakeDataSource) IsConfigChanged() <-chan struct{} {
+	return f.changed
+}
+
+func (f *fakeDataSource) Close() error {
+	close(f.changed)
+	return nil
+}
+
+// set replaces the content without emitting a change event.
+func (f *fakeDataSource) set(content string) {
+	f.mu.Lock()
+	f.content = []byte(content)
+	f.mu.Unlock()
+}
+
+// update replaces the content and emits a change event.
+func (f *fakeDataSource) update(content string) {
+	f.set(content)
+	f.changed <- struct{}{}
+}
+
+var _ DataSource = (*fakeDataSource)(nil)
+
+func TestOnChangeNamed(t *testing.T) {
+	withOptions(t)
+	for _, syncOnChange := range []bool{true, false} {
+		v := New()
+		fired := make(chan string, 16)
+		v.OnChange(func(*Configuration) { fired <- "default" })
+		v.OnChangeNamed("low", -1, func(*Configuration) { fired <- "low" })
+		v.OnChangeNamed("high", 10, func(*Configuration) { fired <- "high" })
+		v.OnChangeNamed("middle", 5, func(*Configuration) { fired <- "replaced" })
+		v.OnChangeNamed("middle", 5, func(*Configuration) { fired <- "middle" })
+		expected := []string{"high", "middle", "default", "low"}
+
+		ds := newFakeDataSource(`foo = "bar"`)
+		defer ds.Close()
+		assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(syncOnChange)))
+		if syncOnChange {
+			assert.Len(t, fired, len(expected))
+		}
+		for _, name := range expected {
+			assert.Equal(t, name, <-fired)
+		}
+
+		ds.update(`foo = "baz"`)
+		for _, name := range expected {
+			assert.Equal(t, name, <-fired)
+		}
+		assert.Equal(t, "baz", v.GetString("foo"))
+	}
+}
+
+func TestReloadNow(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.ErrorIs(t, v.ReloadNow(), ErrNoDataSource)
+
+	fired := make(chan string, 4)
+	v.OnChange(func(c *Configuration) {
+		fired <- c.GetString("foo")
+	})
+	ds := newFakeDataSource(`foo = "bar"`)
+	defer ds.Close()
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+	assert.Equal(t, "bar", <-fired)
+
+	// change the content without emitting a change event
+	ds.mu.Lock()
+	ds.content = []byte(`foo = "baz"`)
+	ds.mu.Unlock()
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, "baz", v.GetString("foo"))
+	assert.Equal(t, "baz", <-fired)
+
+	ds.mu.Lock()
+	ds.content = []byte(`foo = `)
+	ds.mu.Unlock()
+	assert.Error(t, v.ReloadNow())
+	assert.Equal(t, "baz", v.GetString("foo"))
+	assert.Empty(t, fired)
+}
@@ -1,10 +1,20 @@
 package econf
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
+	"github.com/BurntSushi/toml"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
 )
 
 func TestSetKeyDelim(t *testing.T) {
@@ -37,6 +47,52 @@ func TestSub(t *testing.T) {
 	})
 }
 
+func TestSubCopyOnWrite(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("mysql", map[string]interface{}{
+		"primary": map[string]interface{}{"dsn": "a", "pool": 10},
+		"replica": map[string]interface{}{"dsn": "b"},
+	}))
+	sub := v.Sub("mysql")
+	// reads share the parent's maps
+	assert.True(t, sameMap(v.Get("mysql.replica"), sub.Get("replica")))
+
+	assert.NoError(t, sub.Set("primary.dsn", "sub"))
+	assert.NoError(t, sub.Set("replica", map[string]interface{}{"dsn": "c"}))
+	assert.Equal(t, "sub", sub.GetString("primary.dsn"))
+	assert.Equal(t, 10, sub.GetInt("primary.pool"))
+	assert.Equal(t, "a", v.GetString("mysql.primary.dsn"))
+	assert.Equal(t, "b", v.GetString("mysql.replica.dsn"))
+
+	assert.NoError(t, v.Set("mysql.primary.pool", 20))
+	assert.Equal(t, 10, sub.GetInt("primary.pool"))
+	assert.Equal(t, map[string]interface{}{"dsn": "sub", "pool": 10}, sub.GetStringMap("primary"))
+}
+
+// BenchmarkSub compares Sub, which shares the subtree, with deep copying the subtree.
+func BenchmarkSub(b *testing.B) {
+	v := New()
+	tree := make(map[string]interface{})
+	for i := 0; i < 1000; i++ {
+		tree[fmt.Sprintf("k%d", i)] = map[string]interface{}{"value": i}
+	}
+	_ = v.Set("tree", tree)
+
+	b.Run("Sub", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.Sub("tree").GetInt("k1.value")
+		}
+	})
+	b.Run("deep copy", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sub := &Configuration{keyDelim: v.keyDelim, override: deepCopyMap(v.GetStringMap("tree")), keyMap: &sync.Map{}}
+			_ = sub.GetInt("k1.value")
+		}
+	})
+}
+
 func TestSet(t *testing.T) {
 	v := New()
 	key := "a.b.c"
@@ -49,3 +105,599 @@ func TestSet(t *testing.T) {
 	assert.Equal(t, float64(42), v.GetFloat64(key))
 	assert.Equal(t, []string{"42"}, v.GetStringSlice(key))
 }
+
+func TestGetStringMapNotNil(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("a.b", 1))
+
+	m := v.GetStringMap("not.exist")
+	assert.NotNil(t, m)
+	assert.Empty(t, m)
+	assert.NotPanics(t, func() {
+		m["x"] = 1
+	})
+
+	assert.NotNil(t, v.GetStringMap("a.b"))
+	assert.Equal(t, map[string]interface{}{"b": 1}, v.GetStringMapInterface("a"))
+}
+
+func TestGetSecret(t *testing.T) {
+	secretFile := path.Join(t.TempDir(), "db")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("from-file\n"), 0600))
+
+	v := New()
+	assert.NoError(t, v.Set("inline.password", "inline"))
+	assert.NoError(t, v.Set("mounted.password_file", secretFile))
+	assert.NoError(t, v.Set("broken.password_file", path.Join(t.TempDir(), "not-exist")))
+
+	secret, err := v.GetSecret("inline.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "inline", secret)
+
+	secret, err = v.GetSecret("mounted.password")
+	assert.NoError(t, err)
+	assert.Equal(t, "from-file", secret)
+
+	_, err = v.GetSecret("broken.password")
+	assert.Error(t, err)
+
+	_, err = v.GetSecret("missing.password")
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+}
+
+func TestClone(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("a.b", "origin"))
+	assert.NoError(t, v.Set("a.list", []interface{}{"x"}))
+	v.OnChange(func(*Configuration) {})
+
+	clone := v.Clone()
+	assert.Empty(t, clone.onChanges)
+	assert.Equal(t, "origin", clone.GetString("a.b"))
+
+	assert.NoError(t, clone.Set("a.b", "cloned"))
+	assert.NoError(t, clone.Set("a.c", "new"))
+	clone.GetSlice("a.list")[0] = "y"
+
+	assert.Equal(t, "cloned", clone.GetString("a.b"))
+	assert.Equal(t, "origin", v.GetString("a.b"))
+	assert.Nil(t, v.Get("a.c"))
+	assert.Equal(t, []interface{}{"x"}, v.GetSlice("a.list"))
+}
+
+func TestGetFloat32(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("ratio", 0.1))
+	assert.NoError(t, v.Set("pi", "3.14"))
+
+	assert.InDelta(t, float32(0.1), v.GetFloat32("ratio"), 1e-7)
+	assert.Equal(t, float32(3.14), v.GetFloat32("pi"))
+	assert.Equal(t, float32(0), v.GetFloat32("missing"))
+}
+
+func TestDecodeSlice(t *testing.T) {
+	type route struct {
+		Path    string
+		Methods []string
+	}
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+[[routes]]
+path = "/a"
+methods = ["GET"]
+
+[[routes]]
+path = "/b"
+methods = ["GET", "POST"]
+`), toml.Unmarshal))
+
+	routes, err := DecodeSlice[route](v, "routes")
+	assert.NoError(t, err)
+	assert.Equal(t, []route{
+		{Path: "/a", Methods: []string{"GET"}},
+		{Path: "/b", Methods: []string{"GET", "POST"}},
+	}, routes)
+
+	routes, err = DecodeSlice[route](v, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, routes)
+
+	_, err = DecodeSlice[int](v, "routes")
+	assert.Error(t, err)
+}
+
+func TestLoadUnderPrefix(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`name = "app"`), toml.Unmarshal))
+	assert.NoError(t, v.LoadUnderPrefix("vendor.a", []byte("name = \"a\"\n[db]\nhost = \"a-host\""), toml.Unmarshal))
+	assert.NoError(t, v.LoadUnderPrefix("vendor.b", []byte("name = \"b\"\n[db]\nhost = \"b-host\""), toml.Unmarshal))
+
+	assert.Equal(t, "app", v.GetString("name"))
+	assert.Equal(t, "a", v.GetString("vendor.a.name"))
+	assert.Equal(t, "a-host", v.GetString("vendor.a.db.host"))
+	assert.Equal(t, "b", v.GetString("vendor.b.name"))
+	assert.Equal(t, "b-host", v.GetString("vendor.b.db.host"))
+	assert.Nil(t, v.Get("db"))
+	assert.Equal(t, []byte(`name = "app"`), v.raw())
+}
+
+func TestAssertKnownKeys(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.host", "localhost"))
+	assert.NoError(t, v.Set("server.port", 80))
+
+	assert.NoError(t, v.AssertKnownKeys("server", []string{"host", "port"}))
+	assert.NoError(t, v.AssertKnownKeys("server", []string{"host", "port", "timeout"}))
+
+	assert.NoError(t, v.Set("server.prot", 80))
+	assert.NoError(t, v.Set("server.hots", "localhost"))
+	err := v.AssertKnownKeys("server", []string{"host", "port"})
+	assert.True(t, errors.Is(err, ErrUnknownKey))
+	assert.Contains(t, err.Error(), "hots,prot")
+
+	// a missing subtree has nothing unexpected
+	assert.NoError(t, v.AssertKnownKeys("missing", []string{"host"}))
+	assert.Error(t, v.AssertKnownKeys("server.host", []string{"host"}))
+}
+
+func TestGetDurationWithDefault(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("timeout.zero", "0s"))
+	assert.NoError(t, v.Set("timeout.read", "3s"))
+
+	assert.True(t, v.IsSet("timeout.zero"))
+	assert.False(t, v.IsSet("timeout.write"))
+	assert.Equal(t, time.Duration(0), v.GetDurationWithDefault("timeout.zero", time.Second))
+	assert.Equal(t, 3*time.Second, v.GetDurationWithDefault("timeout.read", time.Second))
+	assert.Equal(t, time.Second, v.GetDurationWithDefault("timeout.write", time.Second))
+}
+
+func TestGetStringE(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("app.name", "ego"))
+	assert.NoError(t, v.Set("app.empty", ""))
+	assert.NoError(t, v.Set("app.null", nil))
+	assert.NoError(t, v.Set("app.port", 80))
+
+	str, err := v.GetStringE("app.name")
+	assert.NoError(t, err)
+	assert.Equal(t, "ego", str)
+	str, err = v.GetStringE("app.port")
+	assert.NoError(t, err)
+	assert.Equal(t, "80", str)
+
+	str, err = v.GetStringE("app.empty")
+	assert.NoError(t, err)
+	assert.Equal(t, "", str)
+
+	for _, key := range []string{"app.missing", "app.null"} {
+		_, err = v.GetStringE(key)
+		assert.ErrorIs(t, err, ErrInvalidKey)
+		assert.Contains(t, err.Error(), key)
+	}
+	_, err = v.GetStringE("app")
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrInvalidKey))
+}
+
+func TestGetStringChain(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("db.dsn", "from-config"))
+	assert.NoError(t, v.Set("db.empty", ""))
+	t.Setenv("EGO_TEST_DSN", "from-env")
+	t.Setenv("EGO_TEST_EMPTY", "")
+
+	// config wins over env and default, even if empty
+	assert.Equal(t, "from-config", v.GetStringChain("db.dsn", "EGO_TEST_DSN", "from-default"))
+	assert.Equal(t, "", v.GetStringChain("db.empty", "EGO_TEST_DSN", "from-default"))
+	// env wins over default
+	assert.Equal(t, "from-env", v.GetStringChain("db.missing", "EGO_TEST_DSN", "from-default"))
+	// default if neither is set
+	assert.Equal(t, "from-default", v.GetStringChain("db.missing", "EGO_TEST_EMPTY", "from-default"))
+	assert.Equal(t, "from-default", v.GetStringChain("db.missing", "EGO_TEST_UNSET", "from-default"))
+	assert.Equal(t, "from-default", v.GetStringChain("db.missing", "", "from-default"))
+}
+
+func TestSafeGet(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("a.b", "c"))
+	value, err := v.SafeGet("a.b")
+	assert.NoError(t, err)
+	assert.Equal(t, "c", value)
+
+	// a zero Configuration has no keyMap and panics on Get
+	broken := &Configuration{}
+	assert.Panics(t, func() { broken.Get("a.b") })
+	value, err = broken.SafeGet("a.b")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "a.b")
+	assert.Nil(t, value)
+}
+
+func TestTraverseMaxDepth(t *testing.T) {
+	deep := map[string]interface{}{"leaf": "value"}
+	for i := 0; i < 10000; i++ {
+		deep = map[string]interface{}{"n": deep}
+	}
+	v := New()
+	assert.NotPanics(t, func() {
+		assert.NoError(t, v.apply(map[string]interface{}{"root": deep}))
+	})
+
+	data := v.traverse(v.keyDelim)
+	assert.Len(t, data, 1)
+	for k, leaf := range data {
+		assert.Len(t, strings.Split(k, v.keyDelim), defaultMaxDepth)
+		assert.IsType(t, map[string]interface{}{}, leaf)
+	}
+
+	withOptions(t, WithMaxDepth(2))
+	assert.NoError(t, v.Set("a.b.c", 1))
+	data = v.traverse(v.keyDelim)
+	assert.Equal(t, map[string]interface{}{"c": 1}, data["a.b"])
+}
+
+func TestGetStringMapKeys(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("db.users.dsn", "u"))
+	assert.NoError(t, v.Set("db.orders.dsn", "o"))
+	assert.NoError(t, v.Set("db.audit", "a"))
+
+	assert.Equal(t, []string{"audit", "orders", "users"}, v.GetStringMapKeys("db"))
+	assert.Equal(t, []string{}, v.GetStringMapKeys("db.audit"))
+	assert.Equal(t, []string{}, v.GetStringMapKeys("missing"))
+}
+
+func TestGetStringMapMerged(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("db.pool.max", 20))
+	assert.NoError(t, v.Set("db.dsn", "user@db"))
+	assert.NoError(t, v.Set("db.tags", []interface{}{"b"}))
+	defaults := map[string]interface{}{
+		"dsn":  "localhost",
+		"tags": []interface{}{"a"},
+		"pool": map[string]interface{}{"max": 10, "idle": 2},
+		"log":  map[string]interface{}{"debug": false},
+	}
+
+	merged := v.GetStringMapMerged("db", defaults)
+	assert.Equal(t, map[string]interface{}{
+		"dsn":  "user@db",
+		"tags": []interface{}{"b"},
+		"pool": map[string]interface{}{"max": 20, "idle": 2},
+		"log":  map[string]interface{}{"debug": false},
+	}, merged)
+
+	// the result is a copy of both defaults and config
+	merged["pool"].(map[string]interface{})["idle"] = 100
+	merged["log"].(map[string]interface{})["debug"] = true
+	merged["tags"].([]interface{})[0] = "c"
+	assert.Equal(t, 2, defaults["pool"].(map[string]interface{})["idle"])
+	assert.Equal(t, false, defaults["log"].(map[string]interface{})["debug"])
+	assert.Equal(t, []interface{}{"b"}, v.Get("db.tags"))
+
+	assert.Equal(t, defaults, v.GetStringMapMerged("missing", defaults))
+	assert.Equal(t, map[string]interface{}{"max": 20}, v.GetStringMapMerged("db.pool", nil))
+}
+
+func TestGetNested(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("a.b", 1))
+	assert.NoError(t, v.Set("a.c.d", "2"))
+	assert.NoError(t, v.Set("a.c.e", true))
+	assert.NoError(t, v.Set("ab", "other"))
+	// cache a subtree and a miss
+	_ = v.Get("a.c")
+	_ = v.Get("a.missing")
+
+	assert.Equal(t, map[string]interface{}{
+		"b": 1,
+		"c": map[string]interface{}{"d": "2", "e": true},
+	}, v.GetNested("a"))
+	assert.Equal(t, map[string]interface{}{"d": "2", "e": true}, v.GetNested("a.c"))
+	assert.Equal(t, map[string]interface{}{}, v.GetNested("missing"))
+
+	// Sub and Clone have no flattened leaves of their own
+	assert.Equal(t, map[string]interface{}{"d": "2", "e": true}, v.Sub("a").GetNested("c"))
+	clone := v.Clone()
+	assert.Equal(t, v.GetNested(""), clone.GetNested(""))
+	overlay := v.Overlay(map[string]interface{}{"a": map[string]interface{}{"b": 2}})
+	assert.Equal(t, map[string]interface{}{
+		"b": 2,
+		"c": map[string]interface{}{"d": "2", "e": true},
+	}, overlay.GetNested("a"))
+	overlay.Release()
+
+	// fallback values aren't part of the config
+	v.RegisterFallback(func(key string) (interface{}, bool) {
+		return "fallback", true
+	})
+	assert.Equal(t, "fallback", v.Get("a.fromFallback"))
+	assert.Equal(t, map[string]interface{}{
+		"b": 1,
+		"c": map[string]interface{}{"d": "2", "e": true},
+	}, v.GetNested("a"))
+}
+
+func TestRawSubtrees(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+[plugins.auth]
+issuer = "ego"
+ttl = 60
+
+[plugins.cache]
+size = 128
+tags = ["a", "b"]
+`), toml.Unmarshal))
+
+	raw, err := v.RawSubtrees("plugins")
+	assert.NoError(t, err)
+	assert.Len(t, raw, 2)
+
+	var auth struct {
+		Issuer string `json:"issuer"`
+		TTL    int    `json:"ttl"`
+	}
+	assert.NoError(t, json.Unmarshal(raw["auth"], &auth))
+	assert.Equal(t, "ego", auth.Issuer)
+	assert.Equal(t, 60, auth.TTL)
+
+	var cache struct {
+		Size int      `json:"size"`
+		Tags []string `json:"tags"`
+	}
+	assert.NoError(t, json.Unmarshal(raw["cache"], &cache))
+	assert.Equal(t, 128, cache.Size)
+	assert.Equal(t, []string{"a", "b"}, cache.Tags)
+
+	_, err = v.RawSubtrees("missing")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = v.RawSubtrees("plugins.auth.issuer")
+	assert.Error(t, err)
+}
+
+func TestUnmarshalKeys(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+[server]
+port = 80
+
+[mysql]
+port = "not a number"
+`), toml.Unmarshal))
+
+	var server, mysql, redis struct {
+		Port int
+	}
+	err := v.UnmarshalKeys(map[string]interface{}{
+		"server": &server,
+		"mysql":  &mysql,
+		"redis":  &redis,
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "mysql")
+	assert.Contains(t, err.Error(), "redis")
+	assert.NotContains(t, err.Error(), "server")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	// the valid key is still decoded
+	assert.Equal(t, 80, server.Port)
+
+	assert.NoError(t, v.UnmarshalKeys(map[string]interface{}{"server": &server}))
+
+	// the parallel path decodes and fails the same way
+	var pServer, pMysql, pRedis struct {
+		Port int
+	}
+	pErr := v.UnmarshalKeys(map[string]interface{}{
+		"server": &pServer,
+		"mysql":  &pMysql,
+		"redis":  &pRedis,
+	}, WithDecodeWorkers(2))
+	assert.Equal(t, err.Error(), pErr.Error())
+	assert.Equal(t, server, pServer)
+}
+
+func TestUnmarshalKeysParallel(t *testing.T) {
+	type service struct {
+		Name    string
+		Port    int
+		Tags    []string
+		Timeout time.Duration
+	}
+	v := New()
+	for i := 0; i < 50; i++ {
+		assert.NoError(t, v.Set(fmt.Sprintf("services.s%d", i), map[string]interface{}{
+			"name": fmt.Sprintf("s%d", i), "port": 8000 + i, "tags": []interface{}{"a", "b"}, "timeout": "1s",
+		}))
+	}
+	assert.NoError(t, v.Set("services.s7.port", "invalid"))
+
+	decode := func(opts ...Option) (map[string]*service, error) {
+		out := make(map[string]*service)
+		targets := make(map[string]interface{})
+		for i := 0; i < 50; i++ {
+			key := fmt.Sprintf("services.s%d", i)
+			out[key] = &service{}
+			targets[key] = out[key]
+		}
+		return out, v.UnmarshalKeys(targets, opts...)
+	}
+	sequential, seqErr := decode()
+	parallel, parErr := decode(WithDecodeWorkers(8))
+	assert.Equal(t, sequential, parallel)
+	assert.Error(t, parErr)
+	assert.Equal(t, seqErr.Error(), parErr.Error())
+	assert.Equal(t, 8049, parallel["services.s49"].Port)
+}
+
+// BenchmarkUnmarshalKeys decodes many large subtrees sequentially and in parallel.
+func BenchmarkUnmarshalKeys(b *testing.B) {
+	type item struct {
+		Name  string
+		Value int
+	}
+	v := New()
+	for i := 0; i < 32; i++ {
+		items := make(map[string]interface{})
+		for j := 0; j < 500; j++ {
+			items[fmt.Sprintf("i%d", j)] = map[string]interface{}{"name": "item", "value": j}
+		}
+		_ = v.Set(fmt.Sprintf("subtrees.s%d", i), items)
+	}
+	targets := func() map[string]interface{} {
+		out := make(map[string]interface{})
+		for i := 0; i < 32; i++ {
+			out[fmt.Sprintf("subtrees.s%d", i)] = &map[string]item{}
+		}
+		return out
+	}
+	for _, workers := range []int{1, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_ = v.UnmarshalKeys(targets(), WithDecodeWorkers(workers))
+			}
+		})
+	}
+}
+
+func TestGetStringSliceIndexedMap(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+list: [a, b, c]
+indexed: {2: c, 0: a, 1: b}
+quoted: {"0": a, "1": b, "2": c}
+named: {x: a}
+`), yaml.Unmarshal))
+
+	expected := []string{"a", "b", "c"}
+	assert.Equal(t, expected, v.GetStringSlice("list"))
+	assert.Equal(t, expected, v.GetStringSlice("indexed"))
+	assert.Equal(t, expected, v.GetStringSlice("quoted"))
+	assert.Empty(t, v.GetStringSlice("named"))
+	assert.Equal(t, expected, v.GetStringSliceClean("indexed"))
+}
+
+func TestGetIntStrict(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+port = 8080
+big = 4294967296
+`), toml.Unmarshal))
+	assert.NoError(t, v.Set("huge", uint64(math.MaxUint64)))
+	assert.NoError(t, v.Set("name", "ego"))
+
+	i, err := v.GetIntStrict("port")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, i)
+
+	_, err = v.GetIntStrict("huge")
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = v.GetIntStrict("missing")
+	assert.ErrorIs(t, err, ErrInvalidKey)
+	_, err = v.GetIntStrict("name")
+	assert.Error(t, err)
+
+	// a narrow platform
+	_, err = toIntStrict(v.Get("big"), 32)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = toIntStrict(int64(math.MinInt32)-1, 32)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	i64, err := toIntStrict(int64(math.MaxInt32), 32)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt32), i64)
+	i64, err = toIntStrict(int64(math.MaxInt64), 64)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MaxInt64), i64)
+
+	// JSON numbers are floats
+	assert.NoError(t, v.Load([]byte(`{"json": {"port": 8080, "huge": 1e20, "ratio": 3.7, "negative": -2147483648}}`), json.Unmarshal))
+	i, err = v.GetIntStrict("json.port")
+	assert.NoError(t, err)
+	assert.Equal(t, 8080, i)
+	_, err = v.GetIntStrict("json.huge")
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = v.GetIntStrict("json.ratio")
+	assert.ErrorIs(t, err, ErrNotInteger)
+	i64, err = toIntStrict(v.Get("json.negative"), 32)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(math.MinInt32), i64)
+	_, err = toIntStrict(float64(math.MaxInt32)+1, 32)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = toIntStrict(float32(9.223372e18), 64)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+	_, err = toIntStrict(math.NaN(), 64)
+	assert.ErrorIs(t, err, ErrNotInteger)
+	_, err = toIntStrict(math.Inf(1), 64)
+	assert.ErrorIs(t, err, ErrIntOverflow)
+}
+
+func TestSetKeyDelimDropsCache(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.NoError(t, v.Load([]byte("[server]\nport = 80\n"), toml.Unmarshal))
+	assert.NoError(t, v.Set("server.host", "a"))
+	assert.Equal(t, int64(80), v.Get("server.port"))
+	assert.Nil(t, v.Get("server/port"))
+
+	v.SetKeyDelim("/")
+	assert.Nil(t, v.Get("server.port"))
+	assert.Equal(t, int64(80), v.Get("server/port"))
+	assert.Equal(t, map[string]interface{}{"port": int64(80), "host": "a"}, v.GetNested("server"))
+	assert.Equal(t, sourceSet, v.SourceOf("server/host"))
+	assert.Empty(t, v.SourceOf("server.host"))
+
+	changed := make(chan struct{}, 1)
+	v.WatchKey("server/port", func(*Configuration) { changed <- struct{}{} })
+	assert.NoError(t, v.Set("server/port", int64(81)))
+	<-changed
+	assert.Equal(t, int64(81), v.Get("server/port"))
+	// only a miss is cached under the old key
+	cached, _ := v.keyMap.Load("server.port")
+	assert.Nil(t, cached)
+}
+
+func TestYAMLMergeKeys(t *testing.T) {
+	withOptions(t)
+	v := New()
+	content := []byte(`
+defaults: &defaults
+  timeout: 1s
+  retry:
+    max: 3
+api:
+  <<: *defaults
+  timeout: 2s
+worker:
+  <<: [*defaults]
+copy: *defaults
+`)
+	assert.NoError(t, v.Load(content, yaml.Unmarshal))
+	assert.Equal(t, 3, v.Get("api.retry.max"))
+	assert.Equal(t, "2s", v.GetString("api.timeout"))
+	assert.Equal(t, time.Second, v.GetDuration("worker.timeout"))
+	assert.Equal(t, 3, v.Get("worker.retry.max"))
+	assert.Equal(t, map[string]interface{}{"timeout": "1s", "retry": map[string]interface{}{"max": 3}}, v.GetStringMap("copy"))
+
+	// the anchor and its other aliases don't share the maps of a merged key
+	assert.NoError(t, v.Set("api.retry.max", 5))
+	assert.Equal(t, 5, v.Get("api.retry.max"))
+	assert.Equal(t, 3, v.Get("defaults.retry.max"))
+	assert.Equal(t, 3, v.Get("worker.retry.max"))
+	assert.Equal(t, 3, v.Get("copy.retry.max"))
+	assert.NoError(t, v.Set("defaults.timeout", "3s"))
+	assert.Equal(t, "2s", v.GetString("api.timeout"))
+	assert.Equal(t, "1s", v.GetString("worker.timeout"))
+}
+
+func TestGetStringMapStringNormalized(t *testing.T) {
+	withOptions(t)
+	v := New()
+	content := []byte(`{"headers": {"Content-Type": "json", " X-Request-ID ": "abc", "ACCEPT": "a", "accept": "b", "Retry": 3}}`)
+	assert.NoError(t, v.Load(content, json.Unmarshal))
+	expected := map[string]string{"content-type": "json", "x-request-id": "abc", "accept": "a", "retry": "3"}
+	assert.Equal(t, expected, v.GetStringMapStringNormalized("headers"))
+	// the config keeps its keys
+	assert.Equal(t, "json", v.GetString("headers.Content-Type"))
+	assert.Empty(t, v.GetStringMapStringNormalized("missing"))
+}
@@ -1,16 +1,89 @@
 package econf
 
+import (
+	"github.com/mitchellh/mapstructure"
+)
+
 // Container defines a component instance.
 type Container struct {
 	TagName          string
 	WeaklyTypedInput bool
 	Squash           bool
+	// EnableCSVSlices splits a scalar string on commas when a slice of strings is expected.
+	EnableCSVSlices bool
+	// EnableRefs resolves `${ref:other.key}` tokens in string values during Load.
+	EnableRefs bool
+	// SyncOnChange runs the OnChange callbacks of the first load before LoadFromDataSource returns.
+	SyncOnChange bool
+	// MergeNullDeletes deletes a key when a merged layer sets it to null.
+	MergeNullDeletes bool
+	// MergeAppendKeys are keys whose lists are appended to, instead of replaced, when merging layers.
+	MergeAppendKeys []string
+	// EnableEnvExpansion expands `${VAR}` tokens in string values when they are read.
+	EnableEnvExpansion bool
+	// IgnoreExpansionKeys are keys whose values, and those of the keys under them, are never env expanded.
+	IgnoreExpansionKeys []string
+	// RequiredEnv are environment variables, or keys overridden by EnvOverridePrefix, Load requires to be set.
+	RequiredEnv []string
+	// RequireExpandedEnv makes Load require the variables of the `${VAR}` tokens EnableEnvExpansion expands.
+	RequireExpandedEnv bool
+	// ZeroFields resets the target of UnmarshalKey before decoding.
+	ZeroFields bool
+	// MaxDepth is the maximum nesting depth of maps flattened into keys, 64 if not positive.
+	MaxDepth int
+	// DisableCache makes every read resolve from the config tree instead of the key cache.
+	DisableCache bool
+	// EnableTypedCache caches the cast values of GetString, GetBool, GetInt, GetInt64 and GetFloat64 until the next update,
+	// and the maps of GetStringMapString until their subtree is replaced.
+	EnableTypedCache bool
+	// TreatEmptyAsUnset makes IsSet, and the getters with a default, treat empty string values as not set.
+	TreatEmptyAsUnset bool
+	// CleanStringSlices makes GetStringSlice trim its elements and drop the empty ones.
+	CleanStringSlices bool
+	// DedupStringSlices drops duplicate elements of the slices of GetStringMapStringSlice and map[string][]string fields.
+	DedupStringSlices bool
+	// IndexedSlices makes GetStringMapStringSlice turn maps of indexed keys into slices.
+	IndexedSlices bool
+	// EnableReadTracing counts the reads of each key, as reported by ReadStats.
+	EnableReadTracing bool
+	// DecimalSeparator is the decimal separator of numbers in string values, e.g. `,` for `3,14`.
+	DecimalSeparator string
+	// CacheSize bounds the number of misses, subtrees and fallback values cached by reads, unbounded if not positive.
+	CacheSize int
+	// DecodeWorkers is the number of keys UnmarshalKeys decodes in parallel, sequentially if at most 1.
+	DecodeWorkers int
+	// InternKeys makes the maps of config share one copy of each path segment.
+	InternKeys bool
+	// DiscardRawConfig makes Load drop its content instead of keeping it for RawConfig.
+	DiscardRawConfig bool
+	// DisableDurationHook makes UnmarshalKey stop parsing strings like "1s" into time.Duration fields.
+	DisableDurationHook bool
+	// EnableRadixInts makes UnmarshalKey parse strings with a 0x, 0o, 0 or 0b prefix into integer fields.
+	EnableRadixInts bool
+	// DisableChangeDetection makes updates skip diffing keys, so OnChange callbacks and watchers never fire.
+	// A Configuration reads it once, when created by New and on each LoadFromDataSource.
+	DisableChangeDetection bool
+	// EnvOverridePrefix enables environment variables like <prefix>_SERVER_PORT to override keys like `server.port`.
+	EnvOverridePrefix string
+	// SourceName names the layer loaded by LoadFromDataSource, as reported by SourceOf.
+	// It only applies to the LoadFromDataSource it is passed to.
+	SourceName string
+	// DecodeHooks are extra mapstructure decode hooks used by UnmarshalKey.
+	DecodeHooks []mapstructure.DecodeHookFunc
+	// Metadata receives the keys decoded and left unused by UnmarshalKey.
+	Metadata *mapstructure.Metadata
 }
 
 var defaultContainer = Container{
-	TagName:          "mapstructure",
-	WeaklyTypedInput: false,
-	Squash:           false,
+	TagName:            "mapstructure",
+	WeaklyTypedInput:   false,
+	Squash:             false,
+	EnableCSVSlices:    false,
+	EnableRefs:         false,
+	SyncOnChange:       false,
+	MergeNullDeletes:   false,
+	EnableEnvExpansion: false,
+	ZeroFields:         false,
 }
 
 // GetOptionTagName returns optionTag config of default container
@@ -27,3 +100,143 @@ func GetOptionWeaklyTypedInput() bool {
 func GetOptionSquash() bool {
 	return defaultContainer.Squash
 }
+
+// GetOptionEnableCSVSlices returns EnableCSVSlices config of default container
+func GetOptionEnableCSVSlices() bool {
+	return defaultContainer.EnableCSVSlices
+}
+
+// GetOptionEnableRefs returns EnableRefs config of default container
+func GetOptionEnableRefs() bool {
+	return defaultContainer.EnableRefs
+}
+
+// GetOptionSyncOnChange returns SyncOnChange config of default container
+func GetOptionSyncOnChange() bool {
+	return defaultContainer.SyncOnChange
+}
+
+// GetOptionMergeNullDeletes returns MergeNullDeletes config of default container
+func GetOptionMergeNullDeletes() bool {
+	return defaultContainer.MergeNullDeletes
+}
+
+// GetOptionEnableEnvExpansion returns EnableEnvExpansion config of default container
+func GetOptionEnableEnvExpansion() bool {
+	return defaultContainer.EnableEnvExpansion
+}
+
+// GetOptionZeroFields returns ZeroFields config of default container
+func GetOptionZeroFields() bool {
+	return defaultContainer.ZeroFields
+}
+
+// GetOptionMergeAppendKeys returns MergeAppendKeys config of default container
+func GetOptionMergeAppendKeys() []string {
+	return defaultContainer.MergeAppendKeys
+}
+
+// GetOptionMaxDepth returns MaxDepth config of default container
+func GetOptionMaxDepth() int {
+	return defaultContainer.MaxDepth
+}
+
+// GetOptionDisableCache returns DisableCache config of default container
+func GetOptionDisableCache() bool {
+	return defaultContainer.DisableCache
+}
+
+// GetOptionEnableTypedCache returns EnableTypedCache config of default container
+func GetOptionEnableTypedCache() bool {
+	return defaultContainer.EnableTypedCache
+}
+
+// GetOptionTreatEmptyAsUnset returns TreatEmptyAsUnset config of default container
+func GetOptionTreatEmptyAsUnset() bool {
+	return defaultContainer.TreatEmptyAsUnset
+}
+
+// GetOptionCleanStringSlices returns CleanStringSlices config of default container
+func GetOptionCleanStringSlices() bool {
+	return defaultContainer.CleanStringSlices
+}
+
+// GetOptionDedupStringSlices returns DedupStringSlices config of default container
+func GetOptionDedupStringSlices() bool {
+	return defaultContainer.DedupStringSlices
+}
+
+// GetOptionIgnoreExpansionKeys returns IgnoreExpansionKeys config of default container
+func GetOptionIgnoreExpansionKeys() []string {
+	return defaultContainer.IgnoreExpansionKeys
+}
+
+// GetOptionRequiredEnv returns RequiredEnv config of default container
+func GetOptionRequiredEnv() []string {
+	return defaultContainer.RequiredEnv
+}
+
+// GetOptionRequireExpandedEnv returns RequireExpandedEnv config of default container
+func GetOptionRequireExpandedEnv() bool {
+	return defaultContainer.RequireExpandedEnv
+}
+
+// GetOptionIndexedSlices returns IndexedSlices config of default container
+func GetOptionIndexedSlices() bool {
+	return defaultContainer.IndexedSlices
+}
+
+// GetOptionEnableReadTracing returns EnableReadTracing config of default container
+func GetOptionEnableReadTracing() bool {
+	return defaultContainer.EnableReadTracing
+}
+
+// GetOptionEnvOverridePrefix returns EnvOverridePrefix config of default container
+func GetOptionEnvOverridePrefix() string {
+	return defaultContainer.EnvOverridePrefix
+}
+
+// GetOptionDecimalSeparator returns DecimalSeparator config of default container
+func GetOptionDecimalSeparator() string {
+	return defaultContainer.DecimalSeparator
+}
+
+// GetOptionCacheSize returns CacheSize config of default container
+func GetOptionCacheSize() int {
+	return defaultContainer.CacheSize
+}
+
+// GetOptionDecodeWorkers returns DecodeWorkers config of default container
+func GetOptionDecodeWorkers() int {
+	return defaultContainer.DecodeWorkers
+}
+
+// GetOptionInternKeys returns InternKeys config of default container
+func GetOptionInternKeys() bool {
+	return defaultContainer.InternKeys
+}
+
+// GetOptionDiscardRawConfig returns DiscardRawConfig config of default container
+func GetOptionDiscardRawConfig() bool {
+	return defaultContainer.DiscardRawConfig
+}
+
+// GetOptionMetadata returns Metadata config of default container
+func GetOptionMetadata() *mapstructure.Metadata {
+	return defaultContainer.Metadata
+}
+
+// GetOptionDisableDurationHook returns DisableDurationHook config of default container
+func GetOptionDisableDurationHook() bool {
+	return defaultContainer.DisableDurationHook
+}
+
+// GetOptionEnableRadixInts returns EnableRadixInts config of default container
+func GetOptionEnableRadixInts() bool {
+	return defaultContainer.EnableRadixInts
+}
+
+// GetOptionDisableChangeDetection returns DisableChangeDetection config of default container
+func GetOptionDisableChangeDetection() bool {
+	return defaultContainer.DisableChangeDetection
+}
@@ -11,3 +11,15 @@ func TestContainer(t *testing.T) {
 	assert.Equal(t, false, GetOptionWeaklyTypedInput())
 	assert.Equal(t, false, GetOptionSquash())
 }
+
+// withOptions applies opts to the default container and restores it when the test or benchmark t finishes.
+func withOptions(t testing.TB, opts ...Option) {
+	t.Helper()
+	orig := defaultContainer
+	for _, opt := range opts {
+		opt(&defaultContainer)
+	}
+	t.Cleanup(func() {
+		defaultContainer = orig
+	})
+}
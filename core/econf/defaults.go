@@ -0,0 +1,23 @@
+package econf
+
+// SetDefault sets the default value of key with default defaultConfiguration.
+func SetDefault(key string, value interface{}) {
+	defaultConfiguration.SetDefault(key, value)
+}
+
+// SetDefault sets value as the default of key, returned by reads of key when no layer sets it,
+// or when it is set to an empty string with WithTreatEmptyAsUnset.
+// Defaults are kept apart from the config tree, without the lock, so reading them never waits
+// for a reload. Only reads of key itself see its default, not reads of the keys above it,
+// e.g. the default of `server.port` is not part of GetStringMap("server").
+// The default of an Overlay applies when neither its overrides nor its parent set key.
+func (c *Configuration) SetDefault(key string, value interface{}) {
+	c.defaults.Store(c.canonicalKey(key), deepCopyValue(value))
+	// invalidate typedCache, keeping the generation odd during a refresh
+	c.generation.Add(2)
+}
+
+// defaultValue returns the default of the canonical key, if any.
+func (c *Configuration) defaultValue(key string) (interface{}, bool) {
+	return c.defaults.Load(key)
+}
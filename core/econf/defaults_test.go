@@ -0,0 +1,107 @@
+package econf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDefault(t *testing.T) {
+	withOptions(t, WithTypedCache(true))
+	v := New()
+	assert.Equal(t, 0, v.GetInt("server.port"))
+	v.SetDefault("server.port", 80)
+	assert.Equal(t, 80, v.GetInt("server.port"))
+	assert.True(t, v.IsSet("server.port"))
+	assert.Nil(t, v.Get("server"))
+
+	assert.NoError(t, v.Load([]byte("[server]\nport = 8080\n"), toml.Unmarshal))
+	assert.Equal(t, 8080, v.GetInt("server.port"))
+	assert.NoError(t, v.RegisterAlias("port", "server.port"))
+	v.SetDefault("port", 1)
+	assert.Equal(t, 8080, v.GetInt("port"))
+
+	v = New()
+	v.SetDefault("server.port", 80)
+	assert.Equal(t, 80, v.Overlay(map[string]interface{}{"host": "a"}).GetInt("server.port"))
+
+	// an empty string is only unset with TreatEmptyAsUnset
+	newEmpty := func() *Configuration {
+		v := New()
+		assert.NoError(t, v.Set("a", ""))
+		v.SetDefault("a", "def")
+		return v
+	}
+	v = newEmpty()
+	assert.Equal(t, "", v.GetString("a"))
+	assert.True(t, v.IsSet("a"))
+	t.Run("treat empty as unset", func(t *testing.T) {
+		withOptions(t, WithTreatEmptyAsUnset(true))
+		v := newEmpty()
+		assert.Equal(t, "def", v.GetString("a"))
+		assert.Equal(t, "def", v.Get("a"))
+		assert.True(t, v.IsSet("a"))
+		assert.NoError(t, v.Set("a", "set"))
+		assert.Equal(t, "set", v.GetString("a"))
+	})
+}
+
+func TestSetDefaultDuringReload(t *testing.T) {
+	withOptions(t)
+	v := New()
+	v.SetDefault("timeout", "1s")
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					assert.Equal(t, "1s", v.GetString("timeout"))
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		assert.NoError(t, v.Load([]byte(fmt.Sprintf("port = %d", i)), toml.Unmarshal))
+		v.SetDefault(fmt.Sprintf("other%d", i), i)
+	}
+	close(done)
+	wg.Wait()
+}
+
+// BenchmarkDefaultDuringReload reads a default while the config is reloaded.
+func BenchmarkDefaultDuringReload(b *testing.B) {
+	v := New()
+	v.SetDefault("timeout", "1s")
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-done:
+				return
+			default:
+				_ = v.Load([]byte(fmt.Sprintf("port = %d", i)), toml.Unmarshal)
+			}
+		}
+	}()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			_ = v.GetString("timeout")
+		}
+	})
+	b.StopTimer()
+	close(done)
+	wg.Wait()
+}
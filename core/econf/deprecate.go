@@ -0,0 +1,41 @@
+package econf
+
+import (
+	"sync"
+)
+
+// deprecation is a deprecated key, warned about once.
+type deprecation struct {
+	message string
+	once    sync.Once
+}
+
+// DeprecateKey deprecates key of defaultConfiguration.
+func DeprecateKey(key, message string, replacement ...string) {
+	defaultConfiguration.DeprecateKey(key, message, replacement...)
+}
+
+// DeprecateKey logs message with the logger on the first read of key, suggesting replacement if given.
+// Each deprecated key is warned about once per Configuration, deprecating it again resets that.
+func (c *Configuration) DeprecateKey(key, message string, replacement ...string) {
+	msg := "deprecated config key " + key
+	if message != "" {
+		msg += ": " + message
+	}
+	if len(replacement) > 0 && replacement[0] != "" {
+		msg += ", use " + replacement[0] + " instead"
+	}
+	c.deprecated.Store(key, &deprecation{message: msg})
+}
+
+// warnDeprecated warns once if key is deprecated.
+func (c *Configuration) warnDeprecated(key string) {
+	d, ok := c.deprecated.Load(key)
+	if !ok {
+		return
+	}
+	dep := d.(*deprecation)
+	dep.once.Do(func() {
+		c.warn(dep.message)
+	})
+}
@@ -0,0 +1,35 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeprecateKey(t *testing.T) {
+	v := New()
+	var warnings []string
+	v.SetLogger(func(msg string) {
+		warnings = append(warnings, msg)
+	})
+	assert.NoError(t, v.Set("server.addr", ":80"))
+	assert.NoError(t, v.Set("server.timeout", "1s"))
+	v.DeprecateKey("server.addr", "split into host and port", "server.host")
+	v.DeprecateKey("server.timeout", "")
+
+	assert.Equal(t, ":80", v.GetString("server.addr"))
+	assert.Equal(t, ":80", v.GetString("server.addr"))
+	assert.True(t, v.IsSet("server.addr"))
+	assert.Equal(t, []string{
+		"deprecated config key server.addr: split into host and port, use server.host instead",
+	}, warnings)
+
+	_ = v.GetDuration("server.timeout")
+	_ = v.GetDuration("server.timeout")
+	// a key that isn't deprecated never warns
+	_ = v.Get("server")
+	assert.Equal(t, []string{
+		"deprecated config key server.addr: split into host and port, use server.host instead",
+		"deprecated config key server.timeout",
+	}, warnings)
+}
@@ -0,0 +1,75 @@
+package econf
+
+import (
+	"reflect"
+)
+
+// Diff returns the leaf keys whose values differ between c and other, mapped to
+// their value in c and in other. A value is nil on the side where the key is missing.
+func (c *Configuration) Diff(other *Configuration) map[string][2]interface{} {
+	if c == other {
+		return map[string][2]interface{}{}
+	}
+	c.mu.RLock()
+	leaves := c.traverse(c.keyDelim)
+	c.mu.RUnlock()
+	other.mu.RLock()
+	otherLeaves := other.traverse(other.keyDelim)
+	other.mu.RUnlock()
+	return diffLeaves(leaves, otherLeaves)
+}
+
+// LoadDryRun returns the changes Load of content would make, as Diff of the current and the
+// loaded config, without applying them: config is untouched and no callback is fired.
+// It returns the error Load would return, including the one of a rejecting validator.
+func (c *Configuration) LoadDryRun(content []byte, unmarshal Unmarshaller) (map[string][2]interface{}, error) {
+	configuration, err := c.parse(content, unmarshal)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := c.profileOf(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	leaves := c.traverse(c.keyDelim)
+	base := c.override
+	if profile != nil && c.profileBase != nil {
+		// as commit does, the profile is merged over the config without the active one
+		base = c.profileBase
+	}
+	candidate := deepCopyMap(base)
+	validators := c.validators
+	c.mu.RUnlock()
+	mergeStringMap(candidate, configuration, c.keyDelim, defaultContainer)
+	if profile != nil {
+		mergeStringMap(candidate, profile.values, c.keyDelim, defaultContainer)
+	}
+	if len(validators) > 0 {
+		if err := c.validate(candidate, validators); err != nil {
+			return nil, err
+		}
+	}
+
+	candidateLeaves := make(map[string]interface{})
+	lookup("", candidate, candidateLeaves, c.keyDelim, maxDepth())
+	return diffLeaves(leaves, candidateLeaves), nil
+}
+
+// diffLeaves returns the keys whose values differ between the flattened from and to,
+// mapped to their value in from and in to.
+func diffLeaves(from, to map[string]interface{}) map[string][2]interface{} {
+	changed := make(map[string][2]interface{})
+	for k, v := range from {
+		if tv, ok := to[k]; !ok || !reflect.DeepEqual(v, tv) {
+			changed[k] = [2]interface{}{v, tv}
+		}
+	}
+	for k, v := range to {
+		if _, ok := from[k]; !ok {
+			changed[k] = [2]interface{}{nil, v}
+		}
+	}
+	return changed
+}
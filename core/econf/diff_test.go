@@ -0,0 +1,73 @@
+package econf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	a := New()
+	assert.NoError(t, a.Set("server.port", 80))
+	assert.NoError(t, a.Set("server.host", "localhost"))
+	b := a.Clone()
+	assert.NoError(t, b.Set("server.port", 81))
+	assert.NoError(t, b.Set("server.timeout", "1s"))
+	assert.NoError(t, b.Set("server.host", nil))
+
+	assert.Equal(t, map[string][2]interface{}{
+		"server.port":    {80, 81},
+		"server.timeout": {nil, "1s"},
+		"server.host":    {"localhost", nil},
+	}, a.Diff(b))
+	assert.Empty(t, a.Diff(a.Clone()))
+}
+
+func TestLoadDryRun(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+name = "demo"
+
+[server]
+port = 80
+host = "localhost"
+`), toml.Unmarshal))
+	fired := 0
+	v.OnChange(func(*Configuration) { fired++ })
+	v.Watch("server", func(*Configuration) { fired++ })
+	before := v.Clone()
+
+	changed, err := v.LoadDryRun([]byte(`
+[server]
+port = 8080
+timeout = "1s"
+`), toml.Unmarshal)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][2]interface{}{
+		"server.port":    {int64(80), int64(8080)},
+		"server.timeout": {nil, "1s"},
+	}, changed)
+	assert.True(t, v.Equal(before))
+	assert.Equal(t, int64(80), v.Get("server.port"))
+	assert.Nil(t, v.Get("server.timeout"))
+	assert.Zero(t, fired)
+
+	_, err = v.LoadDryRun([]byte(`port = `), toml.Unmarshal)
+	assert.Error(t, err)
+
+	errRejected := errors.New("rejected")
+	v.RegisterValidator(func(c *Configuration) error {
+		if c.GetInt("server.port") == 0 {
+			return errRejected
+		}
+		return nil
+	})
+	_, err = v.LoadDryRun([]byte(`
+[server]
+port = 0
+`), toml.Unmarshal)
+	assert.ErrorIs(t, err, errRejected)
+	assert.Equal(t, int64(80), v.Get("server.port"))
+}
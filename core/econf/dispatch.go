@@ -0,0 +1,42 @@
+package econf
+
+import "sync"
+
+// dispatcher runs the watcher callbacks of a Configuration one at a time in the order they were
+// queued, on a single goroutine started when callbacks are queued and stopped once they all ran.
+// So frequent reloads don't start a goroutine per watcher per reload, and each watcher sees
+// its notifications in order. The zero value is ready to use.
+type dispatcher struct {
+	mu      sync.Mutex
+	queue   []func()
+	running bool
+}
+
+// dispatch queues fn without blocking.
+func (d *dispatcher) dispatch(fn func()) {
+	d.mu.Lock()
+	d.queue = append(d.queue, fn)
+	if !d.running {
+		d.running = true
+		go d.run()
+	}
+	d.mu.Unlock()
+}
+
+// run calls the queued callbacks until the queue is empty.
+func (d *dispatcher) run() {
+	for {
+		d.mu.Lock()
+		if len(d.queue) == 0 {
+			d.running = false
+			d.queue = nil
+			d.mu.Unlock()
+			return
+		}
+		fn := d.queue[0]
+		d.queue[0] = nil
+		d.queue = d.queue[1:]
+		d.mu.Unlock()
+		fn()
+	}
+}
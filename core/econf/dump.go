@@ -0,0 +1,157 @@
+package econf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// redactedValue replaces the value of a secret key in dumps.
+	redactedValue = "******"
+	// maxDumpValueLen is the length beyond which a value is truncated in dumps.
+	maxDumpValueLen = 128
+)
+
+// RegisterSecretKey registers keys whose values are masked in dumps with default defaultConfiguration.
+func RegisterSecretKey(keys ...string) {
+	defaultConfiguration.RegisterSecretKey(keys...)
+}
+
+// RegisterSecretKey registers keys whose values are masked in dumps such as String.
+// A registered key also masks every key under it, e.g. `mysql` masks `mysql.password`.
+func (c *Configuration) RegisterSecretKey(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.secretKeys == nil {
+		c.secretKeys = make(map[string]struct{})
+	}
+	for _, key := range keys {
+		c.secretKeys[key] = struct{}{}
+	}
+}
+
+// isSecretKey reports if key is, or is under, a registered secret key, with lock held.
+func (c *Configuration) isSecretKey(key string) bool {
+	for secret := range c.secretKeys {
+		if key == secret || strings.HasPrefix(key, secret+c.keyDelim) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the effective config as sorted `key = value` lines of flattened keys.
+// Values of registered secret keys are masked, and long values are truncated.
+func (c *Configuration) String() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	data := c.traverse(c.keyDelim)
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var sb strings.Builder
+	for _, k := range keys {
+		value := redactedValue
+		if !c.isSecretKey(k) {
+			value = fmt.Sprintf("%v", data[k])
+			if len(value) > maxDumpValueLen {
+				// cut on a rune boundary, so a multi-byte character is never split
+				n := maxDumpValueLen
+				for n > 0 && !utf8.RuneStart(value[n]) {
+					n--
+				}
+				value = value[:n] + "..."
+			}
+		}
+		sb.WriteString(k)
+		sb.WriteString(" = ")
+		sb.WriteString(value)
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// Redacted returns a copy of this instance with the values of registered secret keys masked,
+// e.g. to dump it with ToYAML or ToJSON.
+func (c *Configuration) Redacted() *Configuration {
+	out := c.Clone()
+	for secret := range out.secretKeys {
+		redact(out.override, splitKey(secret, out.keyDelim))
+	}
+	return out
+}
+
+// redact masks the value at paths of m if it exists.
+func redact(m map[string]interface{}, paths []string) {
+	for _, k := range paths[:len(paths)-1] {
+		next, ok := m[k].(map[string]interface{})
+		if !ok {
+			return
+		}
+		m = next
+	}
+	if _, ok := m[paths[len(paths)-1]]; ok {
+		m[paths[len(paths)-1]] = redactedValue
+	}
+}
+
+// bufferPool holds the buffers ToYAML and ToJSON encode into, so repeated dumps, e.g. by a debug endpoint,
+// reuse them. Callers get a copy, a pooled buffer is never returned.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// encodePooled encodes with encode into a pooled buffer and returns a copy of the result.
+func encodePooled(encode func(buf *bytes.Buffer) error) ([]byte, error) {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	defer func() {
+		buf.Reset()
+		bufferPool.Put(buf)
+	}()
+	if err := encode(buf); err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), buf.Bytes()...), nil
+}
+
+// ToYAML returns the effective config encoded as YAML, with sorted map keys.
+func (c *Configuration) ToYAML() ([]byte, error) {
+	c.mu.RLock()
+	conf := normalizeValue(c.override)
+	c.mu.RUnlock()
+	return encodePooled(func(buf *bytes.Buffer) error {
+		enc := yaml.NewEncoder(buf)
+		if err := enc.Encode(conf); err != nil {
+			return err
+		}
+		return enc.Close()
+	})
+}
+
+// ToJSON returns the effective config encoded as JSON, with sorted map keys, indented if indent.
+func (c *Configuration) ToJSON(indent bool) ([]byte, error) {
+	c.mu.RLock()
+	conf := normalizeValue(c.override)
+	c.mu.RUnlock()
+	// json.Marshal already encodes into a pooled buffer of its own
+	content, err := json.Marshal(conf)
+	if err != nil || !indent {
+		return content, err
+	}
+	return encodePooled(func(buf *bytes.Buffer) error {
+		return json.Indent(buf, content, "", "  ")
+	})
+}
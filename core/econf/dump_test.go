@@ -0,0 +1,128 @@
+package econf
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestString(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("app.name", "demo"))
+	assert.NoError(t, v.Set("mysql.password", "p@ss"))
+	assert.NoError(t, v.Set("mysql.dsn", "user:p@ss@tcp"))
+	assert.NoError(t, v.Set("app.token", "secret-token"))
+	assert.NoError(t, v.Set("app.long", strings.Repeat("x", 200)))
+	v.RegisterSecretKey("mysql", "app.token")
+
+	assert.Equal(t, "app.long = "+strings.Repeat("x", 128)+"...\n"+
+		"app.name = demo\n"+
+		"app.token = ******\n"+
+		"mysql.dsn = ******\n"+
+		"mysql.password = ******\n"+
+		"server.port = 80\n", fmt.Sprint(v))
+	assert.NotContains(t, v.String(), "p@ss")
+
+	// 3 bytes per rune, so byte 128 is within the 43rd rune
+	v = New()
+	assert.NoError(t, v.Set("name", strings.Repeat("配", 50)))
+	assert.Equal(t, "name = "+strings.Repeat("配", 42)+"...\n", v.String())
+	assert.True(t, utf8.ValidString(v.String()))
+}
+
+func TestToYAMLAndToJSON(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+name = "demo"
+tags = ["a", "b"]
+
+[server]
+port = 80
+timeout = "1s"
+
+[mysql]
+dsn = "user:p@ss@tcp"
+`), toml.Unmarshal))
+	assert.NoError(t, v.Set("server.idle", 30*time.Second))
+	v.RegisterSecretKey("mysql.dsn")
+
+	content, err := v.ToYAML()
+	assert.NoError(t, err)
+	back := New()
+	assert.NoError(t, back.Load(content, yaml.Unmarshal))
+	// durations are dumped as strings
+	assert.NoError(t, v.Set("server.idle", "30s"))
+	assert.True(t, v.Equal(back))
+
+	content, err = v.ToJSON(false)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"mysql":{"dsn":"user:p@ss@tcp"},"name":"demo","server":{"idle":"30s","port":80,"timeout":"1s"},"tags":["a","b"]}`, string(content))
+	assert.True(t, json.Valid(content))
+
+	content, err = v.ToJSON(true)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "{\n  \"mysql\": {\n    \"dsn\""))
+
+	redacted := v.Redacted()
+	content, err = redacted.ToJSON(false)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), `"dsn":"******"`)
+	content, err = redacted.ToYAML()
+	assert.NoError(t, err)
+	assert.NotContains(t, string(content), "p@ss")
+	// the original is untouched
+	assert.Equal(t, "user:p@ss@tcp", v.GetString("mysql.dsn"))
+}
+
+func TestPooledDumps(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load(largeConfig(10), json.Unmarshal))
+	conf := normalizeValue(v.override)
+
+	expected, _ := json.Marshal(conf)
+	content, err := v.ToJSON(false)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, content)
+	expectedIndented, _ := json.MarshalIndent(conf, "", "  ")
+	indented, err := v.ToJSON(true)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedIndented, indented)
+	expected, _ = yaml.Marshal(conf)
+	content, err = v.ToYAML()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, content)
+
+	// returned content is never shared with a pooled buffer
+	for i := range indented {
+		indented[i] = 0
+	}
+	again, err := v.ToJSON(true)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedIndented, again)
+}
+
+// BenchmarkToJSON dumps a config repeatedly, as a debug endpoint does.
+func BenchmarkToJSON(b *testing.B) {
+	v := New()
+	_ = v.Load(largeConfig(1000), json.Unmarshal)
+	b.Run("json", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = v.ToJSON(true)
+		}
+	})
+	b.Run("yaml", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = v.ToYAML()
+		}
+	})
+}
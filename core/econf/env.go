@@ -0,0 +1,140 @@
+package econf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// envPattern matches `${VAR}` tokens. `$VAR` is left untouched, so values such as
+// shell snippets are not mangled.
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ErrMissingEnv defines an error that required environment variables are not set.
+var ErrMissingEnv = errors.New("missing env, maybe not exported in deployment")
+
+// RequireEnv returns an error listing every variable of vars which is missing, e.g. to fail startup
+// when the variables referenced by `${VAR}` tokens of WithEnvExpansion, or the EGO_SERVER_PORT like
+// variables of WithEnvOverride, are not all exported. The error wraps ErrMissingEnv.
+// A variable set to an empty string counts as missing.
+// Load runs the same check with WithRequiredEnv and WithRequireExpandedEnv.
+func RequireEnv(vars ...string) error {
+	var missing []string
+	for _, name := range vars {
+		if os.Getenv(name) == "" {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s, err: %w", strings.Join(missing, ","), ErrMissingEnv)
+}
+
+// requiredEnv returns the variables loading conf requires, see WithRequiredEnv and WithRequireExpandedEnv.
+func (c *Configuration) requiredEnv(conf map[string]interface{}) []string {
+	seen := make(map[string]struct{})
+	var vars []string
+	add := func(name string) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			vars = append(vars, name)
+		}
+	}
+	prefix := defaultContainer.EnvOverridePrefix
+	for _, name := range defaultContainer.RequiredEnv {
+		if prefix != "" && strings.Contains(name, c.keyDelim) {
+			name = envName(prefix, name, c.keyDelim)
+		}
+		add(name)
+	}
+	if !defaultContainer.RequireExpandedEnv || !defaultContainer.EnableEnvExpansion {
+		return vars
+	}
+	leaves := make(map[string]interface{})
+	lookup("", conf, leaves, c.keyDelim, maxDepth())
+	keys := make([]string, 0, len(leaves))
+	for key := range leaves {
+		keys = append(keys, key)
+	}
+	// report the referenced variables in a stable order
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !c.shouldExpand(key) {
+			continue
+		}
+		var strs []string
+		switch v := leaves[key].(type) {
+		case string:
+			strs = []string{v}
+		case []interface{}:
+			for _, e := range v {
+				if str, ok := e.(string); ok {
+					strs = append(strs, str)
+				}
+			}
+		}
+		for _, str := range strs {
+			if isPEMLike(str) {
+				continue
+			}
+			for _, m := range envPattern.FindAllStringSubmatch(str, -1) {
+				add(m[1])
+			}
+		}
+	}
+	return vars
+}
+
+// expandEnv replaces `${VAR}` tokens in str by the value of the environment variable VAR.
+func expandEnv(str string) string {
+	return envPattern.ReplaceAllStringFunc(str, func(token string) string {
+		return os.Getenv(token[2 : len(token)-1])
+	})
+}
+
+// expandEnvOf expands str, the value of key, when env expansion is enabled,
+// unless key is ignored by IgnoreExpansionKeys or str is PEM-like.
+func (c *Configuration) expandEnvOf(key, str string) string {
+	if !c.shouldExpand(key) || isPEMLike(str) {
+		return str
+	}
+	return expandEnv(str)
+}
+
+// expandEnvSliceOf returns a copy of s, the value of key, with expanded elements when env expansion is enabled.
+// s is never modified since it may alias the stored config.
+func (c *Configuration) expandEnvSliceOf(key string, s []string) []string {
+	if !c.shouldExpand(key) {
+		return s
+	}
+	out := make([]string, len(s))
+	for i, str := range s {
+		out[i] = c.expandEnvOf(key, str)
+	}
+	return out
+}
+
+// shouldExpand reports if env expansion is enabled for key.
+// A key in IgnoreExpansionKeys also ignores every key under it.
+func (c *Configuration) shouldExpand(key string) bool {
+	if !defaultContainer.EnableEnvExpansion {
+		return false
+	}
+	for _, ignored := range defaultContainer.IgnoreExpansionKeys {
+		if key == ignored || strings.HasPrefix(key, ignored+c.keyDelim) {
+			return false
+		}
+	}
+	return true
+}
+
+// isPEMLike reports if str looks like a PEM block, e.g. a certificate or a private key,
+// whose content must never be expanded.
+func isPEMLike(str string) bool {
+	begin := strings.Index(str, "-----BEGIN ")
+	return begin >= 0 && strings.Contains(str[begin:], "-----END ")
+}
@@ -0,0 +1,188 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestEnvExpansion(t *testing.T) {
+	t.Setenv("ECONF_TEST_REGION", "us-east")
+	t.Setenv("ECONF_TEST_ZONE", "a")
+
+	v := New()
+	assert.NoError(t, v.Set("app.name", "svc-${ECONF_TEST_REGION}"))
+	assert.NoError(t, v.Set("app.labels", map[string]interface{}{
+		"region": "${ECONF_TEST_REGION}",
+		"zone":   "${ECONF_TEST_REGION}-${ECONF_TEST_ZONE}",
+		"raw":    "$ECONF_TEST_REGION",
+	}))
+	assert.NoError(t, v.Set("app.zones", []interface{}{"${ECONF_TEST_ZONE}", "b"}))
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Equal(t, "svc-${ECONF_TEST_REGION}", v.GetString("app.name"))
+		assert.Equal(t, "${ECONF_TEST_REGION}", v.GetStringMapString("app.labels")["region"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		withOptions(t, WithEnvExpansion(true))
+		assert.Equal(t, "svc-us-east", v.GetString("app.name"))
+		assert.Equal(t, map[string]string{
+			"region": "us-east",
+			"zone":   "us-east-a",
+			"raw":    "$ECONF_TEST_REGION",
+		}, v.GetStringMapString("app.labels"))
+		assert.Equal(t, []string{"a", "b"}, v.GetStringSlice("app.zones"))
+		// the stored value is untouched
+		assert.Equal(t, "svc-${ECONF_TEST_REGION}", v.Get("app.name"))
+	})
+}
+
+func TestEnvExpansionPreservesBlocks(t *testing.T) {
+	t.Setenv("ECONF_TEST_REGION", "us-east")
+	t.Setenv("HOME", "/root")
+	withOptions(t, WithEnvExpansion(true), WithIgnoreExpansionKeys([]string{"hooks"}))
+
+	pem := `-----BEGIN CERTIFICATE-----
+MIIB${ECONF_TEST_REGION}xjCCAWugAwIBAgIU
+-----END CERTIFICATE-----
+`
+	script := `#!/bin/sh
+set -e
+cd "${HOME}/app"
+echo "$1" > ${LOG_FILE:-/dev/null}
+`
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+tls:
+  cert: |
+    -----BEGIN CERTIFICATE-----
+    MIIB${ECONF_TEST_REGION}xjCCAWugAwIBAgIU
+    -----END CERTIFICATE-----
+hooks:
+  deploy:
+    script: |
+      #!/bin/sh
+      set -e
+      cd "${HOME}/app"
+      echo "$1" > ${LOG_FILE:-/dev/null}
+  region: ${ECONF_TEST_REGION}
+region: ${ECONF_TEST_REGION}
+`), yaml.Unmarshal))
+
+	assert.Equal(t, pem, v.GetString("tls.cert"))
+	assert.Equal(t, pem, v.GetStringMapString("tls")["cert"])
+	assert.Equal(t, script, v.GetString("hooks.deploy.script"))
+	assert.Equal(t, map[string]string{"script": script}, v.GetStringMapString("hooks.deploy"))
+	assert.Equal(t, "${ECONF_TEST_REGION}", v.GetString("hooks.region"))
+	// other keys are still expanded
+	assert.Equal(t, "us-east", v.GetString("region"))
+	assert.Equal(t, "/root/x", expandEnv("${HOME}/x"))
+}
+
+func TestGetStringMapStringSliceCoercion(t *testing.T) {
+	t.Setenv("ECONF_TEST_METHODS", "PUT,PATCH")
+	v := New()
+	assert.NoError(t, v.Set("rules", map[string]interface{}{
+		"scalar": "GET",
+		"csv":    "GET, POST",
+		"list":   []interface{}{"GET", "${ECONF_TEST_METHODS}"},
+		"env":    "${ECONF_TEST_METHODS}",
+	}))
+
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, map[string][]string{
+			"scalar": {"GET"},
+			"csv":    {"GET, POST"},
+			"list":   {"GET", "${ECONF_TEST_METHODS}"},
+			"env":    {"${ECONF_TEST_METHODS}"},
+		}, v.GetStringMapStringSlice("rules"))
+	})
+
+	t.Run("csv and env expansion", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true), WithEnvExpansion(true))
+		assert.Equal(t, map[string][]string{
+			"scalar": {"GET"},
+			"csv":    {"GET", "POST"},
+			"list":   {"GET", "PUT,PATCH"},
+			"env":    {"PUT,PATCH"},
+		}, v.GetStringMapStringSlice("rules"))
+	})
+}
+
+func TestGetStringMapStringSliceCopy(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.NoError(t, v.Set("rules", map[string][]string{"get": {"/a", "/b"}}))
+	assert.NoError(t, v.Set("routes", map[string]interface{}{"post": []string{"/c"}}))
+
+	m := v.GetStringMapStringSlice("rules")
+	m["get"][0] = "/x"
+	m["put"] = []string{"/y"}
+	routes := v.GetStringMapStringSlice("routes")
+	routes["post"][0] = "/z"
+
+	assert.Equal(t, map[string][]string{"get": {"/a", "/b"}}, v.GetStringMapStringSlice("rules"))
+	assert.Equal(t, map[string][]string{"post": {"/c"}}, v.GetStringMapStringSlice("routes"))
+}
+
+func TestRequireEnv(t *testing.T) {
+	t.Setenv("ECONF_TEST_REGION", "us-east")
+	t.Setenv("ECONF_TEST_EMPTY", "")
+	assert.NoError(t, RequireEnv())
+	assert.NoError(t, RequireEnv("ECONF_TEST_REGION"))
+
+	err := RequireEnv("ECONF_TEST_MISSING_A", "ECONF_TEST_REGION", "ECONF_TEST_EMPTY", "ECONF_TEST_MISSING_B")
+	assert.ErrorIs(t, err, ErrMissingEnv)
+	assert.ErrorContains(t, err, "ECONF_TEST_MISSING_A,ECONF_TEST_EMPTY,ECONF_TEST_MISSING_B, err:")
+	assert.NotContains(t, err.Error(), "ECONF_TEST_REGION")
+}
+
+func TestWithRequiredEnv(t *testing.T) {
+	t.Setenv("ECONF_TEST_REGION", "us-east")
+	t.Setenv("ECONF_TEST_EMPTY", "")
+	t.Setenv("ECONF_SERVER_PORT", "9090")
+	content := []byte(`
+region = "${ECONF_TEST_REGION}"
+dsn = "${ECONF_TEST_DSN}"
+hosts = ["${ECONF_TEST_HOST}", "b"]
+script = "echo ${ECONF_TEST_IGNORED}"
+`)
+
+	t.Run("default", func(t *testing.T) {
+		withOptions(t, WithEnvExpansion(true))
+		v := New()
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Equal(t, "", v.GetString("dsn"))
+	})
+
+	t.Run("required", func(t *testing.T) {
+		withOptions(t,
+			WithEnvExpansion(true),
+			WithIgnoreExpansionKeys([]string{"script"}),
+			WithEnvOverride("ECONF"),
+			WithRequiredEnv([]string{"ECONF_TEST_MISSING", "ECONF_TEST_EMPTY", "server.port", "server.host"}),
+			WithRequireExpandedEnv(true),
+		)
+		v := New()
+		assert.NoError(t, v.Set("name", "kept"))
+		err := v.Load(content, toml.Unmarshal)
+		assert.ErrorIs(t, err, ErrMissingEnv)
+		// every missing variable in one error, the configured ones first
+		assert.ErrorContains(t, err, "ECONF_TEST_MISSING,ECONF_TEST_EMPTY,ECONF_SERVER_HOST,ECONF_TEST_DSN,ECONF_TEST_HOST, err:")
+		assert.NotContains(t, err.Error(), "ECONF_TEST_IGNORED")
+		// the config is kept
+		assert.Equal(t, "kept", v.GetString("name"))
+		assert.Nil(t, v.Get("dsn"))
+
+		t.Setenv("ECONF_TEST_MISSING", "1")
+		t.Setenv("ECONF_TEST_EMPTY", "1")
+		t.Setenv("ECONF_SERVER_HOST", "localhost")
+		t.Setenv("ECONF_TEST_DSN", "mysql://")
+		t.Setenv("ECONF_TEST_HOST", "a")
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Equal(t, "mysql://", v.GetString("dsn"))
+	})
+}
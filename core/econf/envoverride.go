@@ -0,0 +1,98 @@
+package econf
+
+import (
+	"os"
+	"strings"
+)
+
+// envName returns the name of the environment variable overriding key, e.g. EGO_SERVER_PORT
+// for `server.port` with prefix EGO. Characters other than letters and digits become `_`.
+func envName(prefix, key, sep string) string {
+	var sb strings.Builder
+	sb.WriteString(prefix)
+	for _, path := range splitKey(key, sep) {
+		if path == "" {
+			continue
+		}
+		sb.WriteByte('_')
+		for _, r := range strings.ToUpper(path) {
+			if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+				sb.WriteRune(r)
+			} else {
+				sb.WriteByte('_')
+			}
+		}
+	}
+	return sb.String()
+}
+
+// envSnapshot holds the environment variables named with prefix, as read at generation.
+type envSnapshot struct {
+	prefix     string
+	generation uint64
+	vars       map[string]string
+}
+
+// envOverrides returns the environment variables named with prefix followed by `_`, by name.
+// The environment is read once per update of c, reads in between share the snapshot.
+func (c *Configuration) envOverrides(prefix string) map[string]string {
+	if c.parent != nil {
+		return c.parent.envOverrides(prefix)
+	}
+	// read the generation before the environment, so a snapshot read during an update is never served after it
+	generation := c.generation.Load()
+	if s := c.envVars.Load(); s != nil && s.prefix == prefix && s.generation == generation {
+		return s.vars
+	}
+	vars := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, val, ok := strings.Cut(env, "=")
+		if ok && strings.HasPrefix(name, prefix+"_") {
+			vars[name] = val
+		}
+	}
+	c.envVars.Store(&envSnapshot{prefix: prefix, generation: generation, vars: vars})
+	return vars
+}
+
+// mergeEnvOverrides returns a copy of the subtree value of key with the matching environment
+// variables merged in, or value itself if none match or it is not a subtree.
+// A variable matching a leaf of the subtree overrides it, e.g. EGO_SERVER_READ_TIMEOUT overrides
+// `server.read.timeout`, others are added as children, e.g. `server.read_timeout`.
+func (c *Configuration) mergeEnvOverrides(prefix, key string, value interface{}) interface{} {
+	m, isMap := value.(map[string]interface{})
+	if !isMap && value != nil {
+		return value
+	}
+	envPrefix := envName(prefix, key, c.keyDelim) + "_"
+	var overrides map[string]string
+	for name, val := range c.envOverrides(prefix) {
+		if strings.HasPrefix(name, envPrefix) && len(name) > len(envPrefix) {
+			if overrides == nil {
+				overrides = make(map[string]string)
+			}
+			overrides[name] = val
+		}
+	}
+	if len(overrides) == 0 {
+		return value
+	}
+
+	leaves := make(map[string]interface{})
+	lookup("", m, leaves, c.keyDelim, maxDepth())
+	leafByEnv := make(map[string]string, len(leaves))
+	for leaf := range leaves {
+		leafByEnv[envPrefix+envName("", leaf, c.keyDelim)[1:]] = leaf
+	}
+
+	out := deepCopyMap(m)
+	for name, val := range overrides {
+		if leaf, ok := leafByEnv[name]; ok {
+			paths := splitKey(leaf, c.keyDelim)
+			deepSearch(out, paths[:len(paths)-1])[paths[len(paths)-1]] = val
+			continue
+		}
+		out[strings.ToLower(strings.TrimPrefix(name, envPrefix))] = val
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnvName(t *testing.T) {
+	assert.Equal(t, "EGO_SERVER_PORT", envName("EGO", "server.port", "."))
+	assert.Equal(t, "EGO_SERVER_READ_TIMEOUT", envName("EGO", "server.read-timeout", "."))
+	assert.Equal(t, "EGO_HOSTS_API_EXAMPLE", envName("EGO", `hosts.api\.example`, "."))
+	assert.Equal(t, "EGO", envName("EGO", "", "."))
+}
+
+func TestWithEnvOverride(t *testing.T) {
+	t.Setenv("EGO_SERVER_PORT", "9090")
+	t.Setenv("EGO_SERVER_READ_TIMEOUT", "3s")
+	t.Setenv("EGO_SERVER_MODE", "debug")
+	t.Setenv("EGO_CACHE_ADDR", "redis:6379")
+	v := New()
+	assert.NoError(t, v.Set("server.host", "localhost"))
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("server.read.timeout", "1s"))
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Equal(t, 80, v.GetInt("server.port"))
+		assert.Equal(t, map[string]interface{}{
+			"host": "localhost",
+			"port": 80,
+			"read": map[string]interface{}{"timeout": "1s"},
+		}, v.GetStringMap("server"))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		withOptions(t, WithEnvOverride("EGO"))
+		assert.Equal(t, 9090, v.GetInt("server.port"))
+		assert.Equal(t, "3s", v.GetString("server.read.timeout"))
+		assert.Equal(t, "localhost", v.GetString("server.host"))
+		expected := map[string]interface{}{
+			"host": "localhost",
+			"port": "9090",
+			"read": map[string]interface{}{"timeout": "3s"},
+			"mode": "debug",
+		}
+		assert.Equal(t, expected, v.GetStringMap("server"))
+		assert.Equal(t, map[string]string{
+			"host": "localhost",
+			"port": "9090",
+			"mode": "debug",
+		}, func() map[string]string {
+			m := v.GetStringMapString("server")
+			delete(m, "read")
+			return m
+		}())
+		// a subtree only set by env
+		assert.Equal(t, map[string]interface{}{"addr": "redis:6379"}, v.GetStringMap("cache"))
+		// the stored config is untouched
+		assert.Equal(t, 80, v.GetNested("server")["port"])
+	})
+}
+
+func TestEnvOverrideSnapshot(t *testing.T) {
+	withOptions(t, WithEnvOverride("EGO"))
+	t.Setenv("EGO_SERVER_PORT", "9090")
+	v := New()
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.Equal(t, 9090, v.GetInt("server.port"))
+
+	// the environment is read once per update
+	t.Setenv("EGO_SERVER_PORT", "9091")
+	t.Setenv("EGO_SERVER_MODE", "debug")
+	assert.Equal(t, 9090, v.GetInt("server.port"))
+	assert.Equal(t, map[string]interface{}{"port": "9090"}, v.GetStringMap("server"))
+	assert.NoError(t, v.Set("other", 1))
+	assert.Equal(t, 9091, v.GetInt("server.port"))
+	assert.Equal(t, map[string]interface{}{"port": "9091", "mode": "debug"}, v.GetStringMap("server"))
+
+	// overlays share the snapshot of their parent
+	overlay := v.Overlay(map[string]interface{}{"server": map[string]interface{}{"port": 81}})
+	assert.Equal(t, 9091, overlay.GetInt("server.port"))
+	overlay.Release()
+}
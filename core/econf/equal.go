@@ -0,0 +1,57 @@
+package econf
+
+import (
+	"reflect"
+)
+
+// Equal reports whether c and other hold the same flattened keys and values.
+// Values are compared after normalization, so e.g. an int and an int64 of the same value are equal.
+// Caches, callbacks and watchers are ignored.
+func (c *Configuration) Equal(other *Configuration) bool {
+	if c == other {
+		return true
+	}
+	if other == nil {
+		return false
+	}
+	return reflect.DeepEqual(c.flatten(), other.flatten())
+}
+
+// flatten returns the normalized flattened keys and values of c.
+func (c *Configuration) flatten() map[string]interface{} {
+	c.mu.RLock()
+	data := c.traverse(c.keyDelim)
+	c.mu.RUnlock()
+	for k, v := range data {
+		data[k] = normalizeNumbers(normalizeValue(v))
+	}
+	return data
+}
+
+// normalizeNumbers converts the integers in v to int64 and the floats to float64.
+func normalizeNumbers(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			vv[k] = normalizeNumbers(e)
+		}
+		return vv
+	case []interface{}:
+		for i, e := range vv {
+			vv[i] = normalizeNumbers(e)
+		}
+		return vv
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u <= 1<<63-1 {
+			return int64(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	}
+	return v
+}
@@ -0,0 +1,50 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	content := []byte(`
+[server]
+port = 80
+hosts = ["a", "b"]
+`)
+	a := New()
+	assert.NoError(t, a.Load(content, toml.Unmarshal))
+	b := New()
+	assert.NoError(t, b.Set("server.port", 80))
+	assert.NoError(t, b.Set("server.hosts", []string{"a", "b"}))
+	// warm the cache and register callbacks on one side only
+	_ = a.Get("server")
+	_ = a.Get("missing")
+	a.OnChange(func(*Configuration) {})
+
+	t.Run("equal", func(t *testing.T) {
+		assert.True(t, a.Equal(b))
+		assert.True(t, b.Equal(a))
+		assert.True(t, a.Equal(a))
+		assert.True(t, a.Equal(a.Clone()))
+		assert.True(t, New().Equal(New()))
+	})
+
+	t.Run("subset", func(t *testing.T) {
+		c := b.Clone()
+		assert.NoError(t, c.Set("server.name", "ego"))
+		assert.False(t, a.Equal(c))
+		assert.False(t, c.Equal(a))
+		assert.False(t, a.Equal(nil))
+	})
+
+	t.Run("value differs", func(t *testing.T) {
+		c := b.Clone()
+		assert.NoError(t, c.Set("server.port", 81))
+		assert.False(t, a.Equal(c))
+		c = b.Clone()
+		assert.NoError(t, c.Set("server.port", "80"))
+		assert.False(t, a.Equal(c))
+	})
+}
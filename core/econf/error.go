@@ -0,0 +1,20 @@
+package econf
+
+import "fmt"
+
+// KeyError records an error and the key and operation that caused it.
+type KeyError struct {
+	Key string
+	Op  string
+	Err error
+}
+
+// Error implements error.
+func (e *KeyError) Error() string {
+	return fmt.Sprintf("%s %s, err: %v", e.Op, e.Key, e.Err)
+}
+
+// Unwrap returns the underlying error, so errors.Is(err, ErrInvalidKey) works.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,33 @@
+package econf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyError(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.port", "not-a-number"))
+
+	var out struct {
+		Port int
+	}
+	err := v.UnmarshalKey("missing", &out)
+	var keyErr *KeyError
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "missing", keyErr.Key)
+	assert.Equal(t, "UnmarshalKey", keyErr.Op)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+
+	err = v.UnmarshalKey("server", &out)
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "server", keyErr.Key)
+	assert.False(t, errors.Is(err, ErrInvalidKey))
+
+	_, err = v.GetSecret("db.password")
+	assert.True(t, errors.As(err, &keyErr))
+	assert.Equal(t, "db.password", keyErr.Key)
+	assert.True(t, errors.Is(err, ErrInvalidKey))
+}
@@ -0,0 +1,30 @@
+package econf
+
+// RegisterFallback registers the fallback of missing keys with default defaultConfiguration.
+func RegisterFallback(fn func(key string) (interface{}, bool)) {
+	defaultConfiguration.RegisterFallback(fn)
+}
+
+// RegisterFallback registers fn to supply the keys missing from config, e.g. to fetch them from a
+// remote store on demand. fn runs without holding the lock on the first read of a missing key,
+// and its result, a nil value if fn reports false, is cached like any other read until the next update.
+// A registered fallback replaces the previous one, a nil fn removes it. Either way the misses cached
+// before are dropped, so the next read of a missing key asks fn.
+func (c *Configuration) RegisterFallback(fn func(key string) (interface{}, bool)) {
+	c.mu.Lock()
+	// odd while the misses are dropped, so a read meanwhile doesn't cache one again
+	c.generation.Add(1)
+	c.fallback = fn
+	if c.snapshot.Load() != nil {
+		c.publish()
+	}
+	if c.leavesDeferred.Load() {
+		// keyMap only holds lookups
+		c.dropLookups(nil)
+	} else {
+		c.dropLookups(c.traverse(c.keyDelim))
+	}
+	// invalidates typedCache too
+	c.generation.Add(1)
+	c.mu.Unlock()
+}
@@ -0,0 +1,53 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegisterFallback(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("local.name", "ego"))
+	assert.NoError(t, v.Set("local.null", nil))
+	// a miss cached before the registration falls back too
+	assert.Nil(t, v.Get("remote.port"))
+	assert.Equal(t, 0, v.GetInt("remote.port"))
+	calls := map[string]int{}
+	v.RegisterFallback(func(key string) (interface{}, bool) {
+		calls[key]++
+		if key == "remote.port" {
+			return 8080, true
+		}
+		return nil, false
+	})
+
+	assert.Equal(t, 8080, v.GetInt("remote.port"))
+	assert.Equal(t, 8080, v.GetInt("remote.port"))
+	assert.Equal(t, 1, calls["remote.port"])
+	cached, ok := v.keyMap.Load("remote.port")
+	assert.True(t, ok)
+	assert.Equal(t, 8080, cached)
+
+	// misses are cached too
+	assert.Nil(t, v.Get("remote.missing"))
+	assert.Nil(t, v.Get("remote.missing"))
+	assert.Equal(t, 1, calls["remote.missing"])
+
+	// local keys, even explicit nulls, never fall back
+	assert.Equal(t, "ego", v.GetString("local.name"))
+	assert.Nil(t, v.Get("local.null"))
+	assert.Zero(t, calls["local.name"])
+	assert.Zero(t, calls["local.null"])
+
+	// a local value overrides the fetched one
+	assert.NoError(t, v.Set("remote.port", 9090))
+	assert.Equal(t, 9090, v.GetInt("remote.port"))
+
+	// a new fallback is asked for the misses of the previous one
+	v.RegisterFallback(func(key string) (interface{}, bool) {
+		return "2s", key == "remote.missing"
+	})
+	assert.Equal(t, "2s", v.GetString("remote.missing"))
+	assert.Equal(t, "ego", v.GetString("local.name"))
+}
@@ -0,0 +1,31 @@
+package econf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+)
+
+// gzipMagic is the header of gzip compressed content.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// GzipUnmarshaller returns an Unmarshaller which gunzips content before delegating to inner.
+// Content without the gzip magic bytes is passed to inner unchanged.
+func GzipUnmarshaller(inner Unmarshaller) Unmarshaller {
+	return func(content []byte, v interface{}) error {
+		if !bytes.HasPrefix(content, gzipMagic) {
+			return inner(content, v)
+		}
+		reader, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return fmt.Errorf("GzipUnmarshaller NewReader, err: %w", err)
+		}
+		defer reader.Close()
+		plain, err := io.ReadAll(reader)
+		if err != nil {
+			return fmt.Errorf("GzipUnmarshaller ReadAll, err: %w", err)
+		}
+		return inner(plain, v)
+	}
+}
@@ -0,0 +1,36 @@
+package econf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGzipUnmarshaller(t *testing.T) {
+	content := []byte(`foo = "bar"`)
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	_, err := w.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+
+	t.Run("compressed", func(t *testing.T) {
+		v := New()
+		assert.NoError(t, v.Load(buf.Bytes(), GzipUnmarshaller(toml.Unmarshal)))
+		assert.Equal(t, "bar", v.GetString("foo"))
+	})
+
+	t.Run("plain", func(t *testing.T) {
+		v := New()
+		assert.NoError(t, v.Load(content, GzipUnmarshaller(toml.Unmarshal)))
+		assert.Equal(t, "bar", v.GetString("foo"))
+	})
+
+	t.Run("corrupted", func(t *testing.T) {
+		v := New()
+		assert.Error(t, v.Load(buf.Bytes()[:5], GzipUnmarshaller(toml.Unmarshal)))
+	})
+}
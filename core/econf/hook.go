@@ -0,0 +1,413 @@
+package econf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/mitchellh/mapstructure"
+	"github.com/spf13/cast"
+)
+
+// decodeHookKey identifies the options decodeHook depends on.
+type decodeHookKey struct {
+	// hooks is the backing array of DecodeHooks, which WithDecodeHook never appends to in place
+	hooks            *mapstructure.DecodeHookFunc
+	hooksLen         int
+	decimalSeparator string
+	dedup            bool
+	csv              bool
+	noDuration       bool
+	radix            bool
+}
+
+type decodeHookEntry struct {
+	key  decodeHookKey
+	hook mapstructure.DecodeHookFunc
+}
+
+// lastDecodeHook is the hook composed for the options of the last UnmarshalKey, reused while they don't change.
+var lastDecodeHook atomic.Pointer[decodeHookEntry]
+
+// cachedDecodeHook returns decodeHook(options), composing it again only if options differ from the last call.
+func cachedDecodeHook(options Container) mapstructure.DecodeHookFunc {
+	key := decodeHookKey{
+		hooksLen:         len(options.DecodeHooks),
+		decimalSeparator: options.DecimalSeparator,
+		dedup:            options.DedupStringSlices,
+		csv:              options.EnableCSVSlices,
+		noDuration:       options.DisableDurationHook,
+		radix:            options.EnableRadixInts,
+	}
+	if len(options.DecodeHooks) > 0 {
+		key.hooks = &options.DecodeHooks[0]
+	}
+	if e := lastDecodeHook.Load(); e != nil && e.key == key {
+		return e.hook
+	}
+	hook := decodeHook(options)
+	lastDecodeHook.Store(&decodeHookEntry{key: key, hook: hook})
+	return hook
+}
+
+// decodeHook composes the mapstructure decode hooks enabled by options.
+func decodeHook(options Container) mapstructure.DecodeHookFunc {
+	// hooks from options run first, so they see the raw config value
+	hooks := append([]mapstructure.DecodeHookFunc{}, options.DecodeHooks...)
+	if !options.DisableDurationHook {
+		hooks = append(hooks, mapstructure.StringToTimeDurationHookFunc())
+	}
+	hooks = append(hooks, mapToDurationHookFunc())
+	if options.EnableRadixInts {
+		hooks = append(hooks, stringToRadixIntHookFunc())
+	}
+	hooks = append(hooks,
+		stringToTruthyBoolHookFunc(),
+		rawMessageHookFunc(),
+		epochToTimeHookFunc(),
+		stringToTimeHookFunc(),
+	)
+	if sep := options.DecimalSeparator; sep != "" && sep != "." {
+		hooks = append(hooks, localeFloatHookFunc(sep))
+	}
+	hooks = append(hooks, percentToFloatHookFunc())
+	if options.DedupStringSlices {
+		hooks = append(hooks, dedupStringMapSliceHookFunc(options.EnableCSVSlices))
+	}
+	if options.EnableCSVSlices {
+		hooks = append(hooks, stringToCSVSliceHookFunc())
+	}
+	return mapstructure.ComposeDecodeHookFunc(hooks...)
+}
+
+// stringToCSVSliceHookFunc splits a scalar string on commas when decoding into []string.
+func stringToCSVSliceHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Slice || t.Elem().Kind() != reflect.String {
+			return data, nil
+		}
+		return splitCSV(reflect.ValueOf(data).String()), nil
+	}
+}
+
+// splitCSV splits str on commas and trims whitespace of each element.
+func splitCSV(str string) []string {
+	if str == "" {
+		return []string{}
+	}
+	parts := strings.Split(str, ",")
+	for i, part := range parts {
+		parts[i] = strings.TrimSpace(part)
+	}
+	return parts
+}
+
+// dedupStringMapSliceHookFunc drops the duplicate elements of the slices decoded into a map[string][]string,
+// keeping the first. Scalar strings are split on commas first if csv.
+func dedupStringMapSliceHookFunc(csv bool) mapstructure.DecodeHookFuncType {
+	targetType := reflect.TypeOf(map[string][]string{})
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != targetType || f.Kind() != reflect.Map {
+			return data, nil
+		}
+		m, err := cast.ToStringMapE(data)
+		if err != nil {
+			return data, nil
+		}
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			if str, ok := v.(string); ok && csv {
+				v = splitCSV(str)
+			}
+			if reflect.ValueOf(v).Kind() == reflect.Slice {
+				if s, err := cast.ToStringSliceE(v); err == nil {
+					v = dedupStrings(s)
+				}
+			}
+			out[k] = v
+		}
+		return out, nil
+	}
+}
+
+// dedupStrings returns s without its duplicate elements, keeping the first of each.
+func dedupStrings(s []string) []string {
+	seen := make(map[string]struct{}, len(s))
+	out := make([]string, 0, len(s))
+	for _, str := range s {
+		if _, ok := seen[str]; ok {
+			continue
+		}
+		seen[str] = struct{}{}
+		out = append(out, str)
+	}
+	return out
+}
+
+// stringToRadixIntHookFunc parses a string with 0x, 0o, 0 or 0b prefix when decoding into an integer.
+// Strings that fail to parse are left for mapstructure to report.
+func stringToRadixIntHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String {
+			return data, nil
+		}
+		str := strings.TrimSpace(reflect.ValueOf(data).String())
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			// time.Duration is handled by StringToTimeDurationHookFunc
+			if t == reflect.TypeOf(time.Duration(0)) {
+				return data, nil
+			}
+			if i, err := strconv.ParseInt(str, 0, t.Bits()); err == nil {
+				return i, nil
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i, err := strconv.ParseUint(str, 0, t.Bits()); err == nil {
+				return i, nil
+			}
+		}
+		return data, nil
+	}
+}
+
+// epochToTimeHookFunc decodes a number of Unix seconds into a time.Time.
+func epochToTimeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+		v := reflect.ValueOf(data)
+		switch f.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return time.Unix(v.Int(), 0), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return time.Unix(int64(v.Uint()), 0), nil
+		case reflect.Float32, reflect.Float64:
+			return time.Unix(0, int64(v.Float()*float64(time.Second))), nil
+		}
+		return data, nil
+	}
+}
+
+// stringToTimeHookFunc parses a string into a time.Time in the formats GetTime accepts, such as RFC 3339.
+func stringToTimeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t != reflect.TypeOf(time.Time{}) {
+			return data, nil
+		}
+		return cast.ToTimeE(data)
+	}
+}
+
+// percentToFloatHookFunc parses a string with a trailing `%` into a fraction when decoding into a float.
+func percentToFloatHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || (t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64) {
+			return data, nil
+		}
+		if fraction, ok := parsePercent(reflect.ValueOf(data).String()); ok {
+			return fraction, nil
+		}
+		return data, nil
+	}
+}
+
+// parsePercent parses a percentage like "10%" into a fraction.
+// ok is false if str has no trailing `%` or isn't a number.
+func parsePercent(str string) (float64, bool) {
+	str = strings.TrimSpace(str)
+	if !strings.HasSuffix(str, "%") {
+		return 0, false
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(str, "%")), 64)
+	if err != nil {
+		return 0, false
+	}
+	return f / 100, true
+}
+
+// localeFloatHookFunc parses a string using decimal separator sep when decoding into a float.
+// Strings that still fail to parse, e.g. percentages, are passed on with sep replaced by `.`.
+func localeFloatHookFunc(sep string) mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || (t.Kind() != reflect.Float32 && t.Kind() != reflect.Float64) {
+			return data, nil
+		}
+		str := normalizeDecimal(reflect.ValueOf(data).String(), sep)
+		if v, err := strconv.ParseFloat(strings.TrimSpace(str), 64); err == nil {
+			return v, nil
+		}
+		return str, nil
+	}
+}
+
+// normalizeDecimal replaces the decimal separator sep of str by `.`.
+// str is returned as is if it already contains a `.`, so it isn't parsed twice as a different number.
+func normalizeDecimal(str, sep string) string {
+	if sep == "" || sep == "." || strings.Contains(str, ".") {
+		return str
+	}
+	return strings.Replace(str, sep, ".", 1)
+}
+
+// toLocaleFloat64 casts v to a float64, parsing strings with the decimal separator of the default container.
+func toLocaleFloat64(v interface{}) float64 {
+	f, _ := toLocaleFloat64E(v)
+	return f
+}
+
+// toLocaleFloat64E is toLocaleFloat64 returning the error of the cast.
+func toLocaleFloat64E(v interface{}) (float64, error) {
+	if str, ok := v.(string); ok {
+		v = strings.TrimSpace(normalizeDecimal(str, defaultContainer.DecimalSeparator))
+	}
+	return cast.ToFloat64E(v)
+}
+
+// stringToTruthyBoolHookFunc parses the tokens accepted by GetBoolTruthy when decoding into a bool.
+func stringToTruthyBoolHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if f.Kind() != reflect.String || t.Kind() != reflect.Bool {
+			return data, nil
+		}
+		if b, ok := parseTruthy(reflect.ValueOf(data).String()); ok {
+			return b, nil
+		}
+		return data, nil
+	}
+}
+
+// parseTruthy parses str as one of the truthy or falsy tokens, case-insensitively.
+// ok is false if str is not a known token.
+func parseTruthy(str string) (b bool, ok bool) {
+	switch strings.ToLower(strings.TrimSpace(str)) {
+	case "yes", "y", "on", "enabled", "1", "true", "t":
+		return true, true
+	case "no", "n", "off", "disabled", "0", "false", "f":
+		return false, true
+	}
+	return false, false
+}
+
+// enumHookFunc decodes a string into T by mapping, rejecting unknown tokens.
+func enumHookFunc[T any](mapping map[string]T) mapstructure.DecodeHookFuncType {
+	target := reflect.TypeOf((*T)(nil)).Elem()
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != target || f.Kind() != reflect.String {
+			return data, nil
+		}
+		str := reflect.ValueOf(data).String()
+		v, ok := mapping[str]
+		if !ok {
+			return nil, fmt.Errorf("invalid %s value %q", target, str)
+		}
+		return v, nil
+	}
+}
+
+// rawMessageHookFunc re-marshals the subtree to JSON when decoding into a json.RawMessage,
+// so the field can be decoded lazily later.
+func rawMessageHookFunc() mapstructure.DecodeHookFuncType {
+	rawMessageType := reflect.TypeOf(json.RawMessage{})
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != rawMessageType || f == rawMessageType {
+			return data, nil
+		}
+		return json.Marshal(deepCopyValue(data))
+	}
+}
+
+// durationUnits are the fields recognized in a duration object, e.g. {seconds: 30, millis: 500}.
+var durationUnits = map[string]time.Duration{
+	"days":         24 * time.Hour,
+	"hours":        time.Hour,
+	"minutes":      time.Minute,
+	"seconds":      time.Second,
+	"millis":       time.Millisecond,
+	"milliseconds": time.Millisecond,
+	"micros":       time.Microsecond,
+	"microseconds": time.Microsecond,
+	"nanos":        time.Nanosecond,
+	"nanoseconds":  time.Nanosecond,
+}
+
+// durationFromMap sums the units of a duration object.
+// ok is false if v is not a non-empty map of durationUnits to numbers.
+func durationFromMap(v interface{}) (d time.Duration, ok bool) {
+	m, err := cast.ToStringMapE(v)
+	if err != nil || len(m) == 0 {
+		return 0, false
+	}
+	for k, n := range m {
+		unit, ok := durationUnits[strings.ToLower(k)]
+		if !ok {
+			return 0, false
+		}
+		f, err := cast.ToFloat64E(n)
+		if err != nil {
+			return 0, false
+		}
+		d += time.Duration(f * float64(unit))
+	}
+	return d, true
+}
+
+// mapToDurationHookFunc decodes a duration object into a time.Duration.
+func mapToDurationHookFunc() mapstructure.DecodeHookFuncType {
+	return func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != durationType || f.Kind() != reflect.Map {
+			return data, nil
+		}
+		if d, ok := durationFromMap(data); ok {
+			return d, nil
+		}
+		return data, nil
+	}
+}
+
+// indexedChildren returns a copy of the map value whose children that are maps of indexed keys
+// are replaced by slices, or value itself if it is not a map.
+func indexedChildren(value interface{}) interface{} {
+	m, err := cast.ToStringMapE(value)
+	if err != nil {
+		return value
+	}
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		if s, ok := indexedSlice(v); ok {
+			v = s
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// indexedSlice returns the values of a non-empty map whose keys are all non-negative integers,
+// ordered by index. Gaps between indexes are dropped.
+func indexedSlice(v interface{}) ([]interface{}, bool) {
+	m, err := cast.ToStringMapE(v)
+	if err != nil || len(m) == 0 {
+		return nil, false
+	}
+	indexes := make([]int, 0, len(m))
+	byIndex := make(map[int]interface{}, len(m))
+	for k, e := range m {
+		i, err := strconv.Atoi(k)
+		if err != nil || i < 0 {
+			return nil, false
+		}
+		indexes = append(indexes, i)
+		byIndex[i] = e
+	}
+	sort.Ints(indexes)
+	out := make([]interface{}, len(indexes))
+	for j, i := range indexes {
+		out[j] = byIndex[i]
+	}
+	return out, true
+}
@@ -0,0 +1,347 @@
+package econf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVSlices(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("hosts", "a, b ,c"))
+	assert.NoError(t, v.Set("list", []interface{}{"a,b", "c"}))
+
+	t.Run("disabled", func(t *testing.T) {
+		assert.Equal(t, []string{"a,", "b", ",c"}, v.GetStringSlice("hosts"))
+	})
+
+	t.Run("scalar csv", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true))
+		assert.Equal(t, []string{"a", "b", "c"}, v.GetStringSlice("hosts"))
+
+		var out struct {
+			Hosts []string
+		}
+		assert.NoError(t, v.UnmarshalKey("", &out))
+		assert.Equal(t, []string{"a", "b", "c"}, out.Hosts)
+	})
+
+	t.Run("already a list", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true))
+		assert.Equal(t, []string{"a,b", "c"}, v.GetStringSlice("list"))
+
+		var out struct {
+			List []string
+		}
+		assert.NoError(t, v.UnmarshalKey("", &out))
+		assert.Equal(t, []string{"a,b", "c"}, out.List)
+	})
+}
+
+func TestRadixInt(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("hex", "0xFF"))
+	assert.NoError(t, v.Set("octal", "0644"))
+	assert.NoError(t, v.Set("octal_o", "0o644"))
+	assert.NoError(t, v.Set("binary", "0b101"))
+	assert.NoError(t, v.Set("decimal", "42"))
+	assert.NoError(t, v.Set("number", 7))
+	assert.NoError(t, v.Set("invalid", "0xZZ"))
+	assert.NoError(t, v.Set("mask", "ff"))
+
+	assert.Equal(t, 255, v.GetIntAuto("hex"))
+	assert.Equal(t, 0644, v.GetIntAuto("octal"))
+	assert.Equal(t, 0644, v.GetIntAuto("octal_o"))
+	assert.Equal(t, 5, v.GetIntAuto("binary"))
+	assert.Equal(t, 42, v.GetIntAuto("decimal"))
+	assert.Equal(t, 7, v.GetIntAuto("number"))
+	assert.Equal(t, 0, v.GetIntAuto("invalid"))
+	assert.Equal(t, int64(255), v.GetInt64Radix("hex", 0))
+	assert.Equal(t, int64(0xff), v.GetInt64Radix("mask", 16))
+	assert.Equal(t, int64(420), v.GetInt64Radix("octal", 8))
+
+	type radixInts struct {
+		Hex     int
+		Octal   uint32
+		Binary  int8
+		Decimal int64
+	}
+	withOptions(t)
+	// prefixed strings only decode into integers with WithRadixInts
+	var out radixInts
+	assert.Error(t, v.UnmarshalKey("", &out))
+
+	withOptions(t, WithRadixInts(true))
+	out = radixInts{}
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.Equal(t, 255, out.Hex)
+	assert.Equal(t, uint32(0644), out.Octal)
+	assert.Equal(t, int8(5), out.Binary)
+	assert.Equal(t, int64(42), out.Decimal)
+}
+
+func TestTruthyBool(t *testing.T) {
+	tokens := map[string]bool{
+		"yes": true, "no": false,
+		"on": true, "off": false,
+		"enabled": true, "disabled": false,
+		"y": true, "n": false,
+		"1": true, "0": false,
+		"true": true, "false": false,
+		"YES": true, "Off": false, " Enabled ": true,
+	}
+	for token, expected := range tokens {
+		v := New()
+		assert.NoError(t, v.Set("enabled", token))
+		assert.Equal(t, expected, v.GetBoolTruthy("enabled"), token)
+
+		var out struct {
+			Enabled bool
+		}
+		assert.NoError(t, v.UnmarshalKey("", &out), token)
+		assert.Equal(t, expected, out.Enabled, token)
+	}
+
+	v := New()
+	assert.NoError(t, v.Set("enabled", "yes"))
+	assert.NoError(t, v.Set("native", true))
+	assert.NoError(t, v.Set("unknown", "maybe"))
+	// GetBool stays strict
+	assert.False(t, v.GetBool("enabled"))
+	assert.True(t, v.GetBoolTruthy("native"))
+	assert.False(t, v.GetBoolTruthy("unknown"))
+	assert.False(t, v.GetBoolTruthy("missing"))
+}
+
+type testLevel int
+
+const (
+	testLevelDebug testLevel = iota + 1
+	testLevelInfo
+)
+
+func TestEnum(t *testing.T) {
+	levels := map[string]testLevel{"debug": testLevelDebug, "info": testLevelInfo}
+	type logConfig struct {
+		Level testLevel
+		Name  string
+	}
+
+	v := New()
+	assert.NoError(t, v.Set("valid.level", "info"))
+	assert.NoError(t, v.Set("valid.name", "info"))
+	assert.NoError(t, v.Set("invalid.level", "verbose"))
+
+	var out logConfig
+	assert.NoError(t, v.UnmarshalKey("valid", &out, WithEnum(levels)))
+	assert.Equal(t, logConfig{Level: testLevelInfo, Name: "info"}, out)
+
+	err := v.UnmarshalKey("invalid", &out, WithEnum(levels))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "Level")
+	assert.Contains(t, err.Error(), "verbose")
+
+	// the hook is scoped to the call
+	assert.Empty(t, defaultContainer.DecodeHooks)
+}
+
+func TestRawMessage(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+name = "plugin"
+
+[options]
+size = 3
+tags = ["a", "b"]
+
+[options.nested]
+enabled = true
+`), toml.Unmarshal))
+
+	var out struct {
+		Name    string
+		Options json.RawMessage
+	}
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.Equal(t, "plugin", out.Name)
+	assert.True(t, json.Valid(out.Options))
+	assert.JSONEq(t, `{"size": 3, "tags": ["a", "b"], "nested": {"enabled": true}}`, string(out.Options))
+}
+
+func TestDurationObject(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+read = "1m30s"
+
+[write]
+seconds = 30
+millis = 500
+
+[idle]
+minutes = 1.5
+`), toml.Unmarshal))
+
+	assert.Equal(t, 90*time.Second, v.GetDuration("read"))
+	assert.Equal(t, 30*time.Second+500*time.Millisecond, v.GetDuration("write"))
+	assert.Equal(t, 90*time.Second, v.GetDuration("idle"))
+
+	var out struct {
+		Read  time.Duration
+		Write time.Duration
+		Idle  time.Duration
+	}
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.Equal(t, 90*time.Second, out.Read)
+	assert.Equal(t, 30*time.Second+500*time.Millisecond, out.Write)
+	assert.Equal(t, 90*time.Second, out.Idle)
+
+	// an object with unknown fields is not a duration
+	assert.NoError(t, v.Set("bad", map[string]interface{}{"seconds": 1, "weeks": 1}))
+	assert.Equal(t, time.Duration(0), v.GetDuration("bad"))
+}
+
+func TestEpochTime(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+created = 1700000000
+created_str = "1700000000"
+updated_ms = 1700000000123
+updated_ms_str = "1700000000123"
+bad = "yesterday"
+`), toml.Unmarshal))
+
+	at := time.Unix(1700000000, 0)
+	atMilli := time.UnixMilli(1700000000123)
+	assert.True(t, at.Equal(v.GetTimeUnix("created")))
+	assert.True(t, at.Equal(v.GetTimeUnix("created_str")))
+	assert.True(t, atMilli.Equal(v.GetTimeUnixMilli("updated_ms")))
+	assert.True(t, atMilli.Equal(v.GetTimeUnixMilli("updated_ms_str")))
+	assert.True(t, v.GetTimeUnix("missing").IsZero())
+	assert.True(t, v.GetTimeUnixMilli("missing").IsZero())
+	assert.True(t, v.GetTimeUnix("bad").IsZero())
+
+	var out struct {
+		Created   time.Time
+		CreatedAt time.Time `mapstructure:"created_at"`
+	}
+	assert.NoError(t, v.Set("created_at", 1.5))
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.True(t, at.Equal(out.Created))
+	assert.True(t, time.Unix(1, int64(500*time.Millisecond)).Equal(out.CreatedAt))
+}
+
+func TestPercent(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+sample_rate = "10%"
+error_rate = "0.1"
+ratio = 0.25
+spaced = " 12.5 % "
+bad = "ten%"
+`), toml.Unmarshal))
+
+	assert.InDelta(t, 0.1, v.GetPercent("sample_rate"), 1e-9)
+	assert.InDelta(t, 0.1, v.GetPercent("error_rate"), 1e-9)
+	assert.InDelta(t, 0.25, v.GetPercent("ratio"), 1e-9)
+	assert.InDelta(t, 0.125, v.GetPercent("spaced"), 1e-9)
+	assert.Equal(t, float64(0), v.GetPercent("bad"))
+	assert.Equal(t, float64(0), v.GetPercent("missing"))
+
+	assert.Equal(t, 0.25, v.GetFloat64D("ratio", 0.5))
+	assert.Equal(t, 0.5, v.GetFloat64D("missing", 0.5))
+
+	var out struct {
+		SampleRate float64 `mapstructure:"sample_rate"`
+		Spaced     float32
+		Ratio      float64
+	}
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.InDelta(t, 0.1, out.SampleRate, 1e-9)
+	assert.InDelta(t, 0.125, out.Spaced, 1e-6)
+	assert.Equal(t, 0.25, out.Ratio)
+}
+
+func TestNumberFormat(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+pi = "3,14"
+rate = "12,5%"
+dotted = "2.5"
+native = 1.5
+`), toml.Unmarshal))
+	type config struct {
+		Pi     float64
+		Rate   float64
+		Dotted float32
+		Native float64
+	}
+
+	t.Run("default", func(t *testing.T) {
+		assert.Equal(t, float64(0), v.GetFloat64("pi"))
+		var out config
+		assert.Error(t, v.UnmarshalKey("", &out))
+	})
+
+	t.Run("comma", func(t *testing.T) {
+		withOptions(t, WithNumberFormat(","))
+		assert.Equal(t, 3.14, v.GetFloat64("pi"))
+		assert.Equal(t, 2.5, v.GetFloat64("dotted"))
+		assert.Equal(t, 1.5, v.GetFloat64("native"))
+		assert.Equal(t, 3.14, v.GetFloat64D("pi", 1))
+		assert.Equal(t, float32(3.14), v.GetFloat32("pi"))
+		assert.Equal(t, float32(1.5), v.GetFloat32("native"))
+		assert.Equal(t, 3.14, v.MustGetFloat64("pi"))
+		assert.Equal(t, 3.14, v.GetPercent("pi"))
+		assert.Equal(t, 0.125, v.GetPercent("rate"))
+
+		var out config
+		assert.NoError(t, v.UnmarshalKey("", &out))
+		assert.Equal(t, config{Pi: 3.14, Rate: 0.125, Dotted: 2.5, Native: 1.5}, out)
+	})
+}
+
+func TestCachedDecodeHook(t *testing.T) {
+	withOptions(t)
+	cachedDecodeHook(defaultContainer)
+	first := lastDecodeHook.Load()
+	cachedDecodeHook(defaultContainer)
+	assert.Same(t, first, lastDecodeHook.Load())
+
+	csv := defaultContainer
+	WithCSVSlices(true)(&csv)
+	cachedDecodeHook(csv)
+	assert.NotSame(t, first, lastDecodeHook.Load())
+	hooked := csv
+	WithDecodeHook(stringToTruthyBoolHookFunc())(&hooked)
+	cachedDecodeHook(hooked)
+	assert.Equal(t, 1, lastDecodeHook.Load().key.hooksLen)
+
+	// options passed to UnmarshalKey still apply after a decode with other options
+	v := New()
+	assert.NoError(t, v.Set("hosts", "a,b"))
+	var out struct {
+		Hosts []string
+	}
+	assert.Error(t, v.UnmarshalKey("", &out))
+	assert.NoError(t, v.UnmarshalKey("", &out, WithCSVSlices(true)))
+	assert.Equal(t, []string{"a", "b"}, out.Hosts)
+}
+
+// BenchmarkUnmarshalKey decodes the same key into the same type repeatedly.
+func BenchmarkUnmarshalKey(b *testing.B) {
+	v := New()
+	_ = v.Set("server", map[string]interface{}{"host": "localhost", "port": 80, "timeout": "1s"})
+	type server struct {
+		Host    string
+		Port    int
+		Timeout time.Duration
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var out server
+		_ = v.UnmarshalKey("server", &out)
+	}
+}
@@ -0,0 +1,157 @@
+package econf
+
+import (
+	"reflect"
+
+	"github.com/spf13/cast"
+
+	"github.com/gotomicro/ego/core/util/xmap"
+)
+
+// setAt sets val at paths like update, but only copies the maps along paths and only refreshes
+// the keys under paths and their ancestors, so a Set costs O(size of val) rather than O(total keys).
+func (c *Configuration) setAt(paths []string, val interface{}) error {
+	val = deepCopyValue(val)
+	c.internTree(val)
+	for {
+		c.mu.RLock()
+		version := c.version
+		validators := c.validators
+		root := c.override
+		base := c.profileBase
+		c.mu.RUnlock()
+
+		candidate, old, hadOld := copyPath(root, paths, val)
+		if base != nil {
+			base, _, _ = copyPath(base, paths, val)
+		}
+		if len(validators) > 0 {
+			if err := c.validate(candidate, validators); err != nil {
+				return err
+			}
+		}
+
+		c.mu.Lock()
+		if c.version != version {
+			// committed by another update meanwhile, retry on the new state
+			c.mu.Unlock()
+			continue
+		}
+		c.flattenLeaves()
+		c.override = candidate
+		c.profileBase = base
+		c.version++
+		c.refreshAt(paths, old, hadOld, val)
+		c.mu.Unlock()
+		return nil
+	}
+}
+
+// copyPath returns a copy of m with val set at paths, sharing every map not along paths with m,
+// and the value previously at paths. Along paths, values other than maps are replaced by maps, as deepSearch does.
+func copyPath(m map[string]interface{}, paths []string, val interface{}) (out map[string]interface{}, old interface{}, hadOld bool) {
+	out = shallowCopyMap(m)
+	cur := out
+	for _, k := range paths[:len(paths)-1] {
+		next := shallowCopyMap(cur[k])
+		cur[k] = next
+		cur = next
+	}
+	last := paths[len(paths)-1]
+	old, hadOld = cur[last]
+	cur[last] = val
+	return out, old, hadOld
+}
+
+// shallowCopyMap returns a copy of the map v sharing its values, or an empty map if v is not a map.
+func shallowCopyMap(v interface{}) map[string]interface{} {
+	switch m := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(m)+1)
+		for k, e := range m {
+			out[k] = e
+		}
+		return out
+	case map[interface{}]interface{}:
+		return xmap.ToMapStringInterface(m)
+	}
+	return make(map[string]interface{})
+}
+
+// refreshAt updates keyMap and sources for val set at paths in place of old, and notifies the changed keys,
+// with lock held. Like refresh, cached subtrees and misses under the key and its ancestors are dropped,
+// but cached keys elsewhere are kept.
+func (c *Configuration) refreshAt(paths []string, old interface{}, hadOld bool, val interface{}) {
+	var key string
+	ancestors := make([]string, 0, len(paths)-1)
+	for i, k := range paths {
+		if i > 0 {
+			ancestors = append(ancestors, key)
+		}
+		key = joinKey(key, k, c.keyDelim)
+	}
+	depth := maxDepth() - len(paths) + 1
+	leaves := make(map[string]interface{})
+	nodes := flattenAt(key, val, c.keyDelim, depth, leaves, ancestors)
+	oldLeaves := make(map[string]interface{})
+	if hadOld {
+		nodes = flattenAt(key, old, c.keyDelim, depth, oldLeaves, nodes)
+	}
+
+	detect := !c.disableChangeDetection
+	var changes = make(map[string]interface{})
+	c.generation.Add(1)
+	c.publish()
+	for k, v := range leaves {
+		if detect {
+			orig, ok := c.keyMap.Load(k)
+			if ok && !reflect.DeepEqual(orig, v) {
+				changes[k] = v
+			}
+		}
+		c.keyMap.Store(k, v)
+		c.lookups.remove(k)
+	}
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	for k := range oldLeaves {
+		if _, ok := leaves[k]; !ok {
+			c.keyMap.Delete(k)
+			delete(c.sources, k)
+		}
+	}
+	for _, k := range nodes {
+		if _, ok := leaves[k]; ok {
+			continue
+		}
+		c.keyMap.Delete(k)
+		c.lookups.remove(k)
+		// an ancestor may have been a leaf
+		delete(c.sources, k)
+	}
+	for k := range leaves {
+		c.sources[k] = sourceSet
+	}
+	c.generation.Add(1)
+
+	if len(changes) > 0 {
+		c.notifyChanges(changes)
+	}
+}
+
+// flattenAt flattens val at key into leaves as lookup does at the given depth,
+// and returns nodes with the keys of the maps flattened appended.
+func flattenAt(key string, val interface{}, sep string, depth int, leaves map[string]interface{}, nodes []string) []string {
+	if depth > 1 {
+		if m, err := cast.ToStringMapE(val); err == nil {
+			nodes = append(nodes, key)
+			for k, v := range m {
+				nodes = flattenAt(joinKey(key, k, sep), v, sep, depth-1, leaves, nodes)
+			}
+			return nodes
+		}
+	}
+	leaves[key] = val
+	return nodes
+}
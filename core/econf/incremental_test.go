@@ -0,0 +1,106 @@
+package econf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+// setFull sets val at key refreshing the whole tree, as Set did before incremental refreshes.
+func setFull(c *Configuration, key string, val interface{}) error {
+	paths := splitKey(key, c.keyDelim)
+	return c.update(sourceSet, func(override map[string]interface{}) []string {
+		deepSearch(override, paths[:len(paths)-1])[paths[len(paths)-1]] = val
+		return c.leavesOf(key, val)
+	})
+}
+
+func TestSetIncremental(t *testing.T) {
+	content := []byte(`
+name = "demo"
+
+[server]
+port = 80
+host = "localhost"
+
+[mysql.primary]
+dsn = "a"
+`)
+	steps := []struct {
+		key string
+		val interface{}
+	}{
+		{"server.port", 81},
+		{"server", map[string]interface{}{"port": 82, "tls": map[string]interface{}{"enabled": true}}},
+		{"server.tls", "off"},
+		{"name.first", "ego"},
+		{"mysql.primary", nil},
+		{"mysql", map[string]interface{}{"replica": map[string]interface{}{"dsn": "b"}}},
+		{"mysql.replica.pool", 10},
+		{"new.key", "value"},
+	}
+	reads := []string{"name", "name.first", "server", "server.port", "server.host", "server.tls", "server.tls.enabled",
+		"mysql", "mysql.primary", "mysql.primary.dsn", "mysql.replica", "mysql.replica.dsn", "mysql.replica.pool",
+		"new", "new.key", "missing"}
+
+	incremental, full := New(), New()
+	assert.NoError(t, incremental.Load(content, toml.Unmarshal))
+	assert.NoError(t, full.Load(content, toml.Unmarshal))
+	changed := make(chan string, 16)
+	incremental.WatchKey("server.port", func(*Configuration) { changed <- "server.port" })
+
+	for _, step := range steps {
+		// warm the caches, including subtrees and misses
+		for _, key := range reads {
+			incremental.Get(key)
+			full.Get(key)
+		}
+		assert.NoError(t, incremental.Set(step.key, step.val))
+		assert.NoError(t, setFull(full, step.key, step.val))
+		for _, key := range reads {
+			assert.Equal(t, full.Get(key), incremental.Get(key), "%s after setting %s", key, step.key)
+			assert.Equal(t, full.SourceOf(key), incremental.SourceOf(key), "source of %s after setting %s", key, step.key)
+		}
+		assert.True(t, full.Equal(incremental), step.key)
+	}
+	assert.Equal(t, "server.port", <-changed)
+	assert.Equal(t, "server.port", <-changed)
+}
+
+func TestSetIncrementalCopyOnWrite(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server", map[string]interface{}{"port": 80}))
+	server := v.GetStringMap("server")
+	val := map[string]interface{}{"host": "localhost"}
+	assert.NoError(t, v.Set("mysql", val))
+	assert.NoError(t, v.Set("server.port", 81))
+	// maps handed out and set are never mutated
+	assert.Equal(t, map[string]interface{}{"port": 80}, server)
+	val["host"] = "example.com"
+	assert.Equal(t, "localhost", v.GetString("mysql.host"))
+}
+
+// BenchmarkSet compares a single Set on a large config with refreshing the whole tree.
+func BenchmarkSet(b *testing.B) {
+	v := New()
+	tree := make(map[string]interface{})
+	for i := 0; i < 10000; i++ {
+		tree[fmt.Sprintf("k%d", i)] = map[string]interface{}{"value": i}
+	}
+	_ = v.Set("tree", tree)
+
+	b.Run("incremental", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.Set("server.port", i)
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = setFull(v, "server.port", i)
+		}
+	})
+}
@@ -0,0 +1,45 @@
+package econf
+
+import (
+	"sync"
+)
+
+// internTable holds one copy of each interned string. The zero value is ready to use.
+type internTable struct {
+	mu      sync.Mutex
+	strings map[string]string
+}
+
+// intern returns the copy of s held by t, adding s if it has none.
+func (t *internTable) intern(s string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if interned, ok := t.strings[s]; ok {
+		return interned
+	}
+	if t.strings == nil {
+		t.strings = make(map[string]string)
+	}
+	t.strings[s] = s
+	return s
+}
+
+// internTree replaces the keys of the maps in v by their interned copies if InternKeys is on,
+// so path segments repeated across subtrees, e.g. `name` in each item of a list, share one copy.
+func (c *Configuration) internTree(v interface{}) {
+	if !defaultContainer.InternKeys {
+		return
+	}
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			// assigning an existing key replaces its stored string by the interned one
+			vv[c.interned.intern(k)] = e
+			c.internTree(e)
+		}
+	case []interface{}:
+		for _, e := range vv {
+			c.internTree(e)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package econf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+// largeConfig returns a JSON config of n tenants sharing the same fields.
+func largeConfig(n int) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(`{"tenants": {`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, `"tenant%d": {"database": {"primary_dsn": "dsn-%d", "max_connections": %d}, "feature_flags": {"enable_search": true}}`, i, i, i)
+	}
+	buf.WriteString(`}}`)
+	return buf.Bytes()
+}
+
+func TestWithInternKeys(t *testing.T) {
+	content := largeConfig(1000)
+	plain := New()
+	assert.NoError(t, plain.Load(content, json.Unmarshal))
+
+	withOptions(t, WithInternKeys(true))
+	v := New()
+	assert.NoError(t, v.Load(content, json.Unmarshal))
+	assert.NoError(t, v.Set("tenants.tenant1000", map[string]interface{}{"database": map[string]interface{}{"primary_dsn": "dsn-1000"}}))
+	assert.NoError(t, plain.Set("tenants.tenant1000", map[string]interface{}{"database": map[string]interface{}{"primary_dsn": "dsn-1000"}}))
+
+	assert.True(t, plain.Equal(v))
+	for _, key := range []string{"tenants.tenant0.database.primary_dsn", "tenants.tenant999.database.max_connections",
+		"tenants.tenant1000.database.primary_dsn", "tenants.tenant5.feature_flags", "tenants.tenant5.missing"} {
+		assert.Equal(t, plain.Get(key), v.Get(key), key)
+		assert.Equal(t, plain.SourceOf(key), v.SourceOf(key), key)
+	}
+
+	// the segments of all tenants share one copy
+	segment := func(c *Configuration, tenant string) *byte {
+		for k := range c.GetStringMap("tenants." + tenant) {
+			if k == "database" {
+				return unsafe.StringData(k)
+			}
+		}
+		return nil
+	}
+	assert.Equal(t, segment(v, "tenant1"), segment(v, "tenant2"))
+	assert.Equal(t, segment(v, "tenant1"), segment(v, "tenant1000"))
+}
+
+// BenchmarkInternKeys reports the heap retained by a large config loaded with and without interning.
+func BenchmarkInternKeys(b *testing.B) {
+	content := largeConfig(20000)
+	for _, intern := range []bool{false, true} {
+		b.Run(fmt.Sprintf("intern=%t", intern), func(b *testing.B) {
+			withOptions(b, WithInternKeys(intern))
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				var before, after runtime.MemStats
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				v := New()
+				_ = v.Load(content, yaml.Unmarshal)
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				retained += after.HeapAlloc - before.HeapAlloc
+				runtime.KeepAlive(v)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N)/(1<<20), "retained-MB")
+		})
+	}
+}
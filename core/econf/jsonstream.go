@@ -0,0 +1,132 @@
+package econf
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONStreamUnmarshal is an Unmarshaller decoding JSON token by token with json.Decoder, building the
+// map incrementally. Numbers are decoded as float64, as json.Unmarshal does.
+// Content is already in memory when an Unmarshaller runs, so use LoadJSONStream to avoid holding it.
+func JSONStreamUnmarshal(content []byte, v interface{}) error {
+	value, err := decodeJSONStream(bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	return assignDecoded(value, v)
+}
+
+// LoadJSONStream loads JSON read from r token by token, otherwise as Load does: content identical to the
+// last load is not applied, refs and profiles are resolved, and the content is kept as RawConfig.
+// With WithDiscardRawConfig, unlike LoadFromReader, the content is never held whole: peak memory is about
+// the decoded tree, plus the copy merged into config, plus the largest token.
+func (c *Configuration) LoadJSONStream(r io.Reader) error {
+	hash := sha256.New()
+	var raw bytes.Buffer
+	tee := io.Writer(hash)
+	if !defaultContainer.DiscardRawConfig {
+		tee = io.MultiWriter(hash, &raw)
+	}
+	value, err := decodeJSONStream(io.TeeReader(r, tee))
+	if err != nil {
+		return err
+	}
+	configuration, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("LoadJSONStream, err: top-level JSON value is %T, not an object", value)
+	}
+	var sum [sha256.Size]byte
+	hash.Sum(sum[:0])
+	if c.loadUnchanged("", sum) {
+		return nil
+	}
+	if err := c.prepare(configuration); err != nil {
+		return err
+	}
+	var content []byte
+	if !defaultContainer.DiscardRawConfig {
+		content = raw.Bytes()
+	}
+	return c.loadParsed("", "", sum, content, configuration)
+}
+
+// decodeJSONStream decodes the single JSON value read from r.
+func decodeJSONStream(r io.Reader) (interface{}, error) {
+	dec := json.NewDecoder(r)
+	value, err := decodeJSONValue(dec)
+	if err != nil {
+		return nil, fmt.Errorf("JSONStreamUnmarshal, err: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("JSONStreamUnmarshal, err: unexpected data after the top-level value")
+	}
+	return value, nil
+}
+
+// decodeJSONValue decodes the next value of dec, recursing into objects and arrays.
+func decodeJSONValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	delim, ok := tok.(json.Delim)
+	if !ok {
+		return tok, nil
+	}
+	switch delim {
+	case '{':
+		m := make(map[string]interface{})
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			m[keyTok.(string)] = value
+		}
+		// the closing delimiter
+		_, err = dec.Token()
+		return m, err
+	case '[':
+		s := make([]interface{}, 0)
+		for dec.More() {
+			value, err := decodeJSONValue(dec)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, value)
+		}
+		_, err = dec.Token()
+		return s, err
+	}
+	return nil, fmt.Errorf("unexpected delimiter %v", delim)
+}
+
+// assignDecoded stores value into v, a pointer to a map or an empty interface.
+func assignDecoded(value interface{}, v interface{}) error {
+	switch out := v.(type) {
+	case *interface{}:
+		*out = value
+	case *map[string]interface{}:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("JSONStreamUnmarshal, err: cannot decode %T into a map", value)
+		}
+		if *out == nil {
+			*out = m
+			return nil
+		}
+		for k, e := range m {
+			(*out)[k] = e
+		}
+	default:
+		return fmt.Errorf("JSONStreamUnmarshal, err: unsupported target %T", v)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package econf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeLargeJSON writes a JSON object of n items to w.
+func writeLargeJSON(w io.Writer, n int) {
+	fmt.Fprint(w, `{"name": "large", "items": {`)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			fmt.Fprint(w, ",")
+		}
+		fmt.Fprintf(w, `"item%d": {"name": "name-%d", "value": %d, "enabled": %t, "tags": ["a", "b"], "none": null}`, i, i, i, i%2 == 0)
+	}
+	fmt.Fprint(w, `}}`)
+}
+
+func TestJSONStreamUnmarshal(t *testing.T) {
+	var buf bytes.Buffer
+	writeLargeJSON(&buf, 100)
+
+	var expected, actual map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &expected))
+	assert.NoError(t, JSONStreamUnmarshal(buf.Bytes(), &actual))
+	assert.Equal(t, expected, actual)
+
+	v := New()
+	assert.NoError(t, v.Load(buf.Bytes(), JSONStreamUnmarshal))
+	assert.Equal(t, "name-7", v.GetString("items.item7.name"))
+
+	assert.Error(t, JSONStreamUnmarshal([]byte(`{"a": 1`), &actual))
+	assert.Error(t, JSONStreamUnmarshal([]byte(`{"a": 1} {"b": 2}`), &actual))
+	assert.Error(t, JSONStreamUnmarshal([]byte(`[1, 2]`), &actual))
+}
+
+func TestLoadJSONStream(t *testing.T) {
+	const n = 50000
+	var size countingWriter
+	writeLargeJSON(&size, n)
+
+	pr, pw := io.Pipe()
+	go func() {
+		writeLargeJSON(pw, n)
+		pw.Close()
+	}()
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	value, err := decodeJSONStream(pr)
+	runtime.ReadMemStats(&after)
+	assert.NoError(t, err)
+	assert.Len(t, value.(map[string]interface{})["items"], n)
+	// a rough bound: the decoded tree costs several times the content, which is never held whole
+	assert.Less(t, after.TotalAlloc-before.TotalAlloc, uint64(12*size))
+
+	pr, pw = io.Pipe()
+	go func() {
+		writeLargeJSON(pw, 10)
+		pw.Close()
+	}()
+	v := New()
+	assert.NoError(t, v.LoadJSONStream(pr))
+	assert.Equal(t, "large", v.GetString("name"))
+	assert.Equal(t, 9, v.GetInt("items.item9.value"))
+	assert.True(t, v.GetBool("items.item8.enabled"))
+	assert.Equal(t, []string{"a", "b"}, v.GetStringSlice("items.item8.tags"))
+	assert.Error(t, v.LoadJSONStream(bytes.NewReader([]byte(`["not", "an", "object"]`))))
+}
+
+func TestLoadJSONStreamAsLoad(t *testing.T) {
+	content := []byte(`{"name": "a", "port": 80}`)
+	t.Run("default", func(t *testing.T) {
+		withOptions(t)
+		v := New()
+		var noops []bool
+		v.SetMetricsHook(func(source string, noop bool) {
+			noops = append(noops, noop)
+		})
+		assert.NoError(t, v.LoadJSONStream(bytes.NewReader(content)))
+		assert.Equal(t, content, v.raw())
+		assert.Equal(t, 80, v.GetInt("port"))
+		// the same content again is a noop, like Load
+		assert.NoError(t, v.LoadJSONStream(bytes.NewReader(content)))
+		assert.NoError(t, v.Load(content, json.Unmarshal))
+		assert.Equal(t, []bool{false, true, true}, noops)
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		withOptions(t, WithDiscardRawConfig(true))
+		v := New()
+		assert.NoError(t, v.Load([]byte(`{"port": 81}`), json.Unmarshal))
+		assert.NoError(t, v.LoadJSONStream(bytes.NewReader(content)))
+		assert.Nil(t, v.raw())
+		assert.Equal(t, "a", v.GetString("name"))
+	})
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter int
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	*w += countingWriter(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,97 @@
+package econf
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// keyEscape escapes a delimiter within a key segment, e.g. `hosts.a\.b` is the leaf `a.b` under `hosts`.
+const keyEscape = `\`
+
+// ErrMalformedKey defines an error that a key has an empty segment.
+var ErrMalformedKey = errors.New("malformed key, maybe an empty segment")
+
+// splitKey splits key into its segments on sep, keeping escaped delimiters within a segment.
+// A backslash escapes only the delimiter directly following it, any other backslash is literal.
+func splitKey(key, sep string) []string {
+	escaped := keyEscape + sep
+	if !strings.Contains(key, escaped) {
+		return strings.Split(key, sep)
+	}
+	var paths []string
+	var seg strings.Builder
+	for len(key) > 0 {
+		switch {
+		case strings.HasPrefix(key, escaped):
+			seg.WriteString(sep)
+			key = key[len(escaped):]
+		case strings.HasPrefix(key, sep):
+			paths = append(paths, seg.String())
+			seg.Reset()
+			key = key[len(sep):]
+		default:
+			seg.WriteByte(key[0])
+			key = key[1:]
+		}
+	}
+	return append(paths, seg.String())
+}
+
+// escapeKey escapes the delimiters within a key segment.
+func escapeKey(segment, sep string) string {
+	if !strings.Contains(segment, sep) {
+		return segment
+	}
+	return strings.ReplaceAll(segment, sep, keyEscape+sep)
+}
+
+// joinKey joins a flattened prefix and a key segment, escaping the delimiters within the segment.
+func joinKey(prefix, segment, sep string) string {
+	if prefix == "" {
+		return escapeKey(segment, sep)
+	}
+	return prefix + sep + escapeKey(segment, sep)
+}
+
+// renameKey returns key, delimited by from, delimited by to.
+func renameKey(key, from, to string) string {
+	var renamed string
+	for _, segment := range splitKey(key, from) {
+		renamed = joinKey(renamed, segment, to)
+	}
+	return renamed
+}
+
+// maxCachedPaths bounds the keys whose split path is cached, so reads of unbounded dynamic keys
+// don't grow the cache forever. Keys read once it is full are split on every read.
+const maxCachedPaths = 4096
+
+// pathCache caches the split paths of keys for one delimiter.
+type pathCache struct {
+	delim string
+	paths sync.Map
+	size  atomic.Int64
+}
+
+// splitPath returns splitKey(key, c.keyDelim), cached for repeated reads of key.
+// The returned slice is shared and must not be modified.
+func (c *Configuration) splitPath(key string) []string {
+	delim := c.keyDelim
+	cache := c.paths.Load()
+	if cache == nil || cache.delim != delim {
+		cache = &pathCache{delim: delim}
+		c.paths.Store(cache)
+	}
+	if paths, ok := cache.paths.Load(key); ok {
+		return paths.([]string)
+	}
+	paths := splitKey(key, delim)
+	if cache.size.Load() < maxCachedPaths {
+		if _, loaded := cache.paths.LoadOrStore(key, paths); !loaded {
+			cache.size.Add(1)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,76 @@
+package econf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitKey(t *testing.T) {
+	assert.Equal(t, []string{"a", "b"}, splitKey("a.b", "."))
+	assert.Equal(t, []string{"hosts", "a.b"}, splitKey(`hosts.a\.b`, "."))
+	assert.Equal(t, []string{"a.b.c"}, splitKey(`a\.b\.c`, "."))
+	assert.Equal(t, []string{`a\b`, "c"}, splitKey(`a\b.c`, "."))
+	assert.Equal(t, []string{"hosts", "a::b"}, splitKey(`hosts::a\::b`, "::"))
+	assert.Equal(t, `hosts.a\.b`, joinKey("hosts", "a.b", "."))
+	assert.Equal(t, `a\.b`, joinKey("", "a.b", "."))
+}
+
+func TestSetLiteralDelimiter(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set(`hosts.api\.example\.com`, "10.0.0.1"))
+	assert.NoError(t, v.Set("hosts.api.example", "nested"))
+
+	assert.Equal(t, "10.0.0.1", v.GetString(`hosts.api\.example\.com`))
+	assert.Equal(t, "nested", v.GetString("hosts.api.example"))
+	assert.Equal(t, map[string]interface{}{
+		"api.example.com": "10.0.0.1",
+		"api":             map[string]interface{}{"example": "nested"},
+	}, v.GetStringMap("hosts"))
+	assert.Equal(t, v.GetStringMap("hosts"), v.GetNested("hosts"))
+
+	// a fresh read without the cache resolves the same leaf
+	assert.Equal(t, "10.0.0.1", v.Clone().GetString(`hosts.api\.example\.com`))
+
+	// updates of the literal leaf refresh its cached value
+	assert.NoError(t, v.Set(`hosts.api\.example\.com`, "10.0.0.2"))
+	assert.Equal(t, "10.0.0.2", v.GetString(`hosts.api\.example\.com`))
+}
+
+func TestSetInvalidKey(t *testing.T) {
+	v := New()
+	for _, key := range []string{"", "a..b", ".a", "a."} {
+		err := v.Set(key, 1)
+		assert.ErrorIs(t, err, ErrMalformedKey, key)
+	}
+	assert.Empty(t, v.GetStringMap(""))
+}
+
+func TestSplitPath(t *testing.T) {
+	v := New()
+	assert.Equal(t, []string{"a", "b"}, v.splitPath("a.b"))
+	assert.Equal(t, []string{"a", "b"}, v.splitPath("a.b"))
+	assert.EqualValues(t, 1, v.paths.Load().size.Load())
+
+	v.SetKeyDelim("/")
+	assert.Equal(t, []string{"a.b"}, v.splitPath("a.b"))
+	assert.Equal(t, []string{"a", "b"}, v.splitPath("a/b"))
+
+	for i := 0; i < maxCachedPaths+10; i++ {
+		v.splitPath(fmt.Sprintf("k/%d", i))
+	}
+	assert.EqualValues(t, maxCachedPaths, v.paths.Load().size.Load())
+	assert.Equal(t, []string{"k", "x"}, v.splitPath("k/x"))
+}
+
+// BenchmarkGetUncached reads the same key with the key cache disabled, so find resolves it from the tree.
+func BenchmarkGetUncached(b *testing.B) {
+	withOptions(b, WithDisableCache(true))
+	v := New()
+	_ = v.Set("server.http.port", 80)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = v.Get("server.http.port")
+	}
+}
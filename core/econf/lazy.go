@@ -0,0 +1,35 @@
+package econf
+
+// refreshInitial is refresh for the first update of an empty instance, with lock held: nothing can
+// have changed, so the leaves of override aren't flattened into keyMap until flattenLeaves is needed,
+// e.g. to detect the changes of the next update. It does nothing and returns false if keyMap holds
+// lookups cached before, whose changes refresh must notify.
+func (c *Configuration) refreshInitial() bool {
+	cached := false
+	c.keyMap.Range(func(_, _ interface{}) bool {
+		cached = true
+		return false
+	})
+	if cached {
+		return false
+	}
+	c.generation.Add(1)
+	c.publish()
+	c.lookups.reset()
+	c.leavesDeferred.Store(true)
+	c.generation.Add(1)
+	return true
+}
+
+// flattenLeaves stores the leaves of override into keyMap if refreshInitial deferred it, with lock held.
+// Lookups cached meanwhile are kept, leaves are untracked from lookups so they're never evicted.
+func (c *Configuration) flattenLeaves() {
+	if !c.leavesDeferred.Load() {
+		return
+	}
+	for k, v := range c.traverse(c.keyDelim) {
+		c.keyMap.Store(k, v)
+		c.lookups.remove(k)
+	}
+	c.leavesDeferred.Store(false)
+}
@@ -0,0 +1,61 @@
+package econf
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInitialLoadDefersLeaves(t *testing.T) {
+	withOptions(t)
+	v := New()
+	changed := make(chan struct{}, 4)
+	v.WatchKey("server.port", func(*Configuration) { changed <- struct{}{} })
+	assert.NoError(t, v.Load([]byte("[server]\nport = 80\nhost = \"a\"\n"), toml.Unmarshal))
+	assert.True(t, v.leavesDeferred.Load())
+	_, ok := v.keyMap.Load("server.host")
+	assert.False(t, ok)
+	assert.Equal(t, int64(80), v.Get("server.port"))
+
+	// the first reload still detects changes against the initial load
+	assert.NoError(t, v.Load([]byte("[server]\nport = 81\nhost = \"a\"\n"), toml.Unmarshal))
+	assert.False(t, v.leavesDeferred.Load())
+	<-changed
+	assert.Equal(t, int64(81), v.Get("server.port"))
+	assert.Empty(t, changed)
+
+	v = New()
+	assert.NoError(t, v.Load([]byte("[server]\nport = 80\n"), toml.Unmarshal))
+	v.WatchKey("server.port", func(*Configuration) { changed <- struct{}{} })
+	assert.NoError(t, v.Set("server.port", 82))
+	<-changed
+
+	v = New()
+	assert.NoError(t, v.Load([]byte("[server]\nport = 80\n"), toml.Unmarshal))
+	// GetNested reads the tree, it doesn't need the leaves
+	assert.Equal(t, map[string]interface{}{"port": int64(80)}, v.GetNested("server"))
+	assert.True(t, v.leavesDeferred.Load())
+}
+
+func TestInitialLoadNotifiesCachedLookups(t *testing.T) {
+	withOptions(t)
+	v := New()
+	changed := make(chan struct{}, 1)
+	v.WatchKey("port", func(*Configuration) { changed <- struct{}{} })
+	// the cached miss makes the initial load a change of port
+	assert.Nil(t, v.Get("port"))
+	assert.NoError(t, v.Load([]byte("port = 80"), toml.Unmarshal))
+	assert.False(t, v.leavesDeferred.Load())
+	<-changed
+}
+
+// BenchmarkInitialLoad loads a large config into a new instance.
+func BenchmarkInitialLoad(b *testing.B) {
+	content := largeConfig(20000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = New().Load(content, json.Unmarshal)
+	}
+}
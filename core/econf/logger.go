@@ -0,0 +1,37 @@
+package econf
+
+import (
+	"log"
+)
+
+// Logger logs the warnings of econf, e.g. on the read of a deprecated key.
+// It can wrap any logger, e.g. `func(msg string) { elog.Warn(msg) }`.
+type Logger func(msg string)
+
+// defaultLogger logs to the standard logger.
+func defaultLogger(msg string) {
+	log.Println("[" + PackageName + "] " + msg)
+}
+
+// SetLogger sets the logger of defaultConfiguration.
+func SetLogger(logger Logger) {
+	defaultConfiguration.SetLogger(logger)
+}
+
+// SetLogger sets the logger of warnings, the standard logger if nil.
+func (c *Configuration) SetLogger(logger Logger) {
+	c.mu.Lock()
+	c.logger = logger
+	c.mu.Unlock()
+}
+
+// warn logs msg with the logger of c.
+func (c *Configuration) warn(msg string) {
+	c.mu.RLock()
+	logger := c.logger
+	c.mu.RUnlock()
+	if logger == nil {
+		logger = defaultLogger
+	}
+	logger(msg)
+}
@@ -0,0 +1,91 @@
+package econf
+
+import (
+	"container/list"
+	"sync"
+)
+
+// lruKeys tracks the keys cached by find on top of the leaves, in least recently used order,
+// so they can be evicted once CacheSize is exceeded. Leaves are never tracked, since refresh
+// relies on them to detect changes. The zero value is ready to use.
+type lruKeys struct {
+	mu    sync.Mutex
+	order *list.List
+	items map[string]*list.Element
+}
+
+// add tracks key as the most recently used and returns the least recently used keys beyond capacity.
+func (l *lruKeys) add(key string, capacity int) (evicted []string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.items == nil {
+		l.order = list.New()
+		l.items = make(map[string]*list.Element)
+	}
+	if e, ok := l.items[key]; ok {
+		l.order.MoveToFront(e)
+		return nil
+	}
+	l.items[key] = l.order.PushFront(key)
+	for l.order.Len() > capacity {
+		e := l.order.Back()
+		l.order.Remove(e)
+		delete(l.items, e.Value.(string))
+		evicted = append(evicted, e.Value.(string))
+	}
+	return evicted
+}
+
+// touch marks key as the most recently used if it is tracked.
+func (l *lruKeys) touch(key string) {
+	l.mu.Lock()
+	if e, ok := l.items[key]; ok {
+		l.order.MoveToFront(e)
+	}
+	l.mu.Unlock()
+}
+
+// remove stops tracking key.
+func (l *lruKeys) remove(key string) {
+	l.mu.Lock()
+	if e, ok := l.items[key]; ok {
+		l.order.Remove(e)
+		delete(l.items, key)
+	}
+	l.mu.Unlock()
+}
+
+// reset stops tracking every key.
+func (l *lruKeys) reset() {
+	l.mu.Lock()
+	l.order, l.items = nil, nil
+	l.mu.Unlock()
+}
+
+// len returns the number of tracked keys.
+func (l *lruKeys) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.items)
+}
+
+// cacheLookup caches the value find looked up for key, evicting the least recently used lookups
+// if CacheSize is set, with generation read before the lookup.
+func (c *Configuration) cacheLookup(key string, value interface{}, generation uint64) {
+	capacity := defaultContainer.CacheSize
+	if capacity > 0 {
+		// track before storing, so a refresh resetting the tracked keys meanwhile is detected below
+		for _, k := range c.lookups.add(key, capacity) {
+			c.keyMap.Delete(k)
+			c.typedCache.Delete(k)
+		}
+	}
+	c.keyMap.Store(key, value)
+	if c.generation.Load() != generation {
+		// a refresh started meanwhile and may have missed the stale value
+		c.keyMap.Delete(key)
+		if capacity > 0 {
+			c.lookups.remove(key)
+		}
+	}
+}
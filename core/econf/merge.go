@@ -0,0 +1,195 @@
+package econf
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/gotomicro/ego/core/util/xmap"
+)
+
+// merger merges config layers like xmap.MergeStringMap, honoring the merge options of container.
+type merger struct {
+	container Container
+	sep       string
+	// appended are the lists the layer merged by mergeCopy appended before, by flattened key
+	appended map[string][]interface{}
+	// appending receives the lists mergeCopy appends, by flattened key, if not nil
+	appending map[string][]interface{}
+}
+
+// mergeStringMap merges src into dest with the options of container.
+func mergeStringMap(dest, src map[string]interface{}, sep string, container Container) {
+	m := merger{container: container, sep: sep}
+	m.merge("", dest, src)
+}
+
+func (m merger) merge(prefix string, dest, src map[string]interface{}) {
+	for sk, sv := range src {
+		key := sk
+		if prefix != "" {
+			key = prefix + m.sep + sk
+		}
+		if sv == nil && m.container.MergeNullDeletes {
+			delete(dest, sk)
+			continue
+		}
+		tv, ok := dest[sk]
+		if !ok {
+			// val不存在时，直接赋值
+			dest[sk] = sv
+			continue
+		}
+
+		// 类型不同时，保留原值
+		if reflect.TypeOf(sv) != reflect.TypeOf(tv) {
+			continue
+		}
+
+		switch ttv := tv.(type) {
+		case map[interface{}]interface{}:
+			stv := xmap.ToMapStringInterface(ttv)
+			m.merge(key, stv, xmap.ToMapStringInterface(sv.(map[interface{}]interface{})))
+			dest[sk] = stv
+		case map[string]interface{}:
+			m.merge(key, ttv, sv.(map[string]interface{}))
+		case []interface{}:
+			if m.isAppendKey(key) {
+				merged := make([]interface{}, 0, len(ttv)+len(sv.([]interface{})))
+				dest[sk] = append(append(merged, ttv...), sv.([]interface{})...)
+				continue
+			}
+			dest[sk] = sv
+		default:
+			dest[sk] = sv
+		}
+	}
+}
+
+// mergeCopy returns dest merged with src as merge would merge src into a deep copy of dest, without
+// modifying dest: only the maps of dest which src changes are copied, the others are shared by the result.
+// changed reports if any was, otherwise dest itself is returned.
+func (m merger) mergeCopy(prefix string, dest, src map[string]interface{}) (out map[string]interface{}, changed bool) {
+	out = dest
+	own := func() {
+		if !changed {
+			out = shallowCopyMap(dest)
+			changed = true
+		}
+	}
+	set := func(k string, v interface{}) {
+		own()
+		out[k] = v
+	}
+	for sk, sv := range src {
+		key := sk
+		if prefix != "" {
+			key = prefix + m.sep + sk
+		}
+		tv, ok := dest[sk]
+		if sv == nil && m.container.MergeNullDeletes {
+			if ok {
+				own()
+				delete(out, sk)
+			}
+			continue
+		}
+		if !ok {
+			m.recordAppends(key, sv)
+			set(sk, sv)
+			continue
+		}
+		if ttv, isMap := tv.(map[interface{}]interface{}); isMap {
+			// as converted by deepCopyMap
+			tv = xmap.ToMapStringInterface(ttv)
+		}
+		if reflect.TypeOf(sv) != reflect.TypeOf(tv) {
+			continue
+		}
+
+		switch ttv := tv.(type) {
+		case map[string]interface{}:
+			if merged, mergedChanged := m.mergeCopy(key, ttv, sv.(map[string]interface{})); mergedChanged {
+				set(sk, merged)
+			}
+		case []interface{}:
+			if m.isAppendKey(key) {
+				if merged := m.appendList(key, ttv, sv.([]interface{})); !reflect.DeepEqual(merged, ttv) {
+					set(sk, merged)
+				}
+				continue
+			}
+			if !reflect.DeepEqual(ttv, sv) {
+				set(sk, sv)
+			}
+		default:
+			if !sameScalar(tv, sv) {
+				set(sk, sv)
+			}
+		}
+	}
+	return out, changed
+}
+
+// appendList returns list with the elements src appends at key, replacing the ones appended before
+// by the same layer if they are still found in list, e.g. unless a Set replaced list meanwhile.
+func (m merger) appendList(key string, list, src []interface{}) []interface{} {
+	if m.appending != nil {
+		m.appending[key] = src
+	}
+	merged := make([]interface{}, 0, len(list)+len(src))
+	if prev := m.appended[key]; len(prev) > 0 {
+		if i := lastIndexOfRun(list, prev); i >= 0 {
+			merged = append(append(merged, list[:i]...), src...)
+			return append(merged, list[i+len(prev):]...)
+		}
+	}
+	return append(append(merged, list...), src...)
+}
+
+// recordAppends records the lists under key in v, a value set as is, as appended by the layer.
+func (m merger) recordAppends(key string, v interface{}) {
+	if m.appending == nil {
+		return
+	}
+	switch vv := v.(type) {
+	case []interface{}:
+		if m.isAppendKey(key) {
+			m.appending[key] = vv
+		}
+	case map[string]interface{}:
+		for k, e := range vv {
+			m.recordAppends(key+m.sep+k, e)
+		}
+	}
+}
+
+// lastIndexOfRun returns the index of the last run of elements equal to run in list, or -1 if none.
+func lastIndexOfRun(list, run []interface{}) int {
+	for i := len(list) - len(run); i >= 0; i-- {
+		if reflect.DeepEqual(list[i:i+len(run)], run) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameScalar reports if a and b, of the same type, are equal comparable values.
+func sameScalar(a, b interface{}) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	if !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// isAppendKey reports if key is, or is under, one of MergeAppendKeys.
+func (m merger) isAppendKey(key string) bool {
+	for _, appendKey := range m.container.MergeAppendKeys {
+		if key == appendKey || strings.HasPrefix(key, appendKey+m.sep) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package econf
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeNullDeletes(t *testing.T) {
+	base := []byte(`{"a": {"b": 1, "c": 2}, "d": 3}`)
+	layer := []byte(`{"a": {"b": null}, "d": null, "e": null}`)
+
+	t.Run("keep null", func(t *testing.T) {
+		v := New()
+		assert.NoError(t, v.Load(base, json.Unmarshal))
+		assert.NoError(t, v.Load(layer, json.Unmarshal))
+		assert.Equal(t, float64(1), v.Get("a.b"))
+		assert.Equal(t, float64(3), v.Get("d"))
+		content, err := v.ToJSON(false)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"a": {"b": 1, "c": 2}, "d": 3, "e": null}`, string(content))
+	})
+
+	t.Run("delete on null", func(t *testing.T) {
+		withOptions(t, WithMergeNullDeletes(true))
+		v := New()
+		assert.NoError(t, v.Load(base, json.Unmarshal))
+		assert.NoError(t, v.Load(layer, json.Unmarshal))
+		assert.Equal(t, map[string]interface{}{"c": float64(2)}, v.GetStringMap("a"))
+		content, err := v.ToJSON(false)
+		assert.NoError(t, err)
+		assert.JSONEq(t, `{"a": {"c": 2}}`, string(content))
+	})
+}
+
+func TestMergeAppendKeys(t *testing.T) {
+	base := []byte(`{"middlewares": ["log"], "plugins": ["a"], "rules": {"get": ["/a"]}}`)
+	layer := []byte(`{"middlewares": ["auth"], "plugins": ["b"], "rules": {"get": ["/b"]}}`)
+
+	t.Run("replace", func(t *testing.T) {
+		v := New()
+		assert.NoError(t, v.Load(base, json.Unmarshal))
+		assert.NoError(t, v.Load(layer, json.Unmarshal))
+		assert.Equal(t, []string{"auth"}, v.GetStringSlice("middlewares"))
+		assert.Equal(t, map[string][]string{"get": {"/b"}}, v.GetStringMapStringSlice("rules"))
+	})
+
+	t.Run("append", func(t *testing.T) {
+		withOptions(t, WithMergeAppendKeys([]string{"middlewares", "rules"}))
+		v := New()
+		assert.NoError(t, v.Load(base, json.Unmarshal))
+		assert.NoError(t, v.Load(layer, json.Unmarshal))
+		assert.Equal(t, []string{"log", "auth"}, v.GetStringSlice("middlewares"))
+		assert.Equal(t, map[string][]string{"get": {"/a", "/b"}}, v.GetStringMapStringSlice("rules"))
+		// not configured keys are still replaced
+		assert.Equal(t, []string{"b"}, v.GetStringSlice("plugins"))
+	})
+}
+
+func TestMergeAppendKeysReload(t *testing.T) {
+	withOptions(t, WithMergeAppendKeys([]string{"middlewares", "rules"}))
+	v := New()
+	assert.NoError(t, v.Load([]byte(`{"middlewares": ["log"], "rules": {"get": ["/a"]}}`), json.Unmarshal))
+	ds := newFakeDataSource(`{"middlewares": ["auth"], "rules": {"get": ["/b"]}}`)
+	defer ds.Close()
+	assert.NoError(t, v.LoadFromDataSource(ds, json.Unmarshal, WithSyncOnChange(true)))
+	assert.Equal(t, []string{"log", "auth"}, v.GetStringSlice("middlewares"))
+
+	// a reload of the layer replaces the elements it appended
+	ds.set(`{"middlewares": ["auth", "trace"], "rules": {"get": ["/b"]}}`)
+	assert.NoError(t, v.ReloadNow())
+	ds.set(`{"middlewares": ["auth", "trace"], "rules": {"get": ["/b"], "post": ["/c"]}}`)
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, []string{"log", "auth", "trace"}, v.GetStringSlice("middlewares"))
+	assert.Equal(t, map[string][]string{"get": {"/a", "/b"}, "post": {"/c"}}, v.GetStringMapStringSlice("rules"))
+	ds.set(`{"middlewares": ["trace"], "rules": {"post": ["/c"]}}`)
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, []string{"log", "trace"}, v.GetStringSlice("middlewares"))
+	assert.Equal(t, map[string][]string{"get": {"/a", "/b"}, "post": {"/c"}}, v.GetStringMapStringSlice("rules"))
+
+	// another layer still appends
+	assert.NoError(t, v.Load([]byte(`{"middlewares": ["cors"]}`), json.Unmarshal))
+	assert.Equal(t, []string{"log", "trace", "cors"}, v.GetStringSlice("middlewares"))
+}
+
+func TestMergeCopy(t *testing.T) {
+	dest := func() map[string]interface{} {
+		return map[string]interface{}{
+			"server": map[string]interface{}{"host": "a", "port": 80},
+			"tenants": map[string]interface{}{
+				"t1": map[string]interface{}{"name": "first"},
+				"t2": map[interface{}]interface{}{"name": "second"},
+			},
+			"middlewares": []interface{}{"log"},
+			"plugins":     []interface{}{"a"},
+			"nil":         nil,
+			"removed":     1,
+			"typed":       1,
+		}
+	}
+	layers := []map[string]interface{}{
+		{},
+		{"server": map[string]interface{}{"host": "a"}},
+		{"server": map[string]interface{}{"port": 81}, "new": map[string]interface{}{"k": "v"}},
+		{"tenants": map[string]interface{}{"t2": map[string]interface{}{"name": "renamed"}}},
+		{"tenants": map[string]interface{}{"t2": map[interface{}]interface{}{"name": "renamed"}}},
+		{"middlewares": []interface{}{"auth"}, "plugins": []interface{}{"a"}},
+		{"removed": nil, "nil": nil, "missing": nil},
+		{"typed": "string", "server": "scalar"},
+	}
+	for _, container := range []Container{
+		{},
+		{MergeNullDeletes: true, MergeAppendKeys: []string{"middlewares"}},
+	} {
+		m := merger{container: container, sep: "."}
+		for _, layer := range layers {
+			orig := dest()
+			expected := deepCopyMap(orig)
+			m.merge("", expected, layer)
+
+			merged, changed := m.mergeCopy("", orig, layer)
+			assert.Equal(t, expected, deepCopyMap(merged), "%v", layer)
+			// dest is never modified
+			assert.Equal(t, dest(), orig)
+			if !changed {
+				assert.Equal(t, reflect.ValueOf(orig).Pointer(), reflect.ValueOf(merged).Pointer())
+			}
+		}
+	}
+
+	// unchanged subtrees are shared
+	orig := dest()
+	merged, changed := merger{sep: "."}.mergeCopy("", orig, map[string]interface{}{"server": map[string]interface{}{"port": 81}})
+	assert.True(t, changed)
+	shared := func(key string) bool {
+		return reflect.ValueOf(orig[key]).Pointer() == reflect.ValueOf(merged[key]).Pointer()
+	}
+	assert.True(t, shared("tenants"))
+	assert.False(t, shared("server"))
+
+	_, changed = merger{sep: "."}.mergeCopy("", orig, map[string]interface{}{"server": map[string]interface{}{"port": 80}})
+	assert.False(t, changed)
+}
+
+// BenchmarkReload reloads a large config with a single changed value.
+func BenchmarkReload(b *testing.B) {
+	contents := [][]byte{largeConfig(20000), bytes.Replace(largeConfig(20000), []byte(`"dsn-7"`), []byte(`"dsn-changed"`), 1)}
+	v := New()
+	_ = v.Load(contents[0], json.Unmarshal)
+	_ = v.Load(contents[1], json.Unmarshal)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = v.Load(contents[i%2], json.Unmarshal)
+	}
+}
@@ -0,0 +1,35 @@
+package econf
+
+// MetricsHook is called after each successful load of content, e.g. by a reload of the DataSource,
+// to export metrics. noop reports if the content was identical to the last one loaded from source,
+// so it was skipped without being parsed or applied, and no callback fired.
+type MetricsHook func(source string, noop bool)
+
+// loadState identifies the content loaded last from source, and the version it committed.
+type loadState struct {
+	source  string
+	sum     [32]byte
+	version uint64
+}
+
+// SetMetricsHook sets the metrics hook of defaultConfiguration.
+func SetMetricsHook(hook MetricsHook) {
+	defaultConfiguration.SetMetricsHook(hook)
+}
+
+// SetMetricsHook sets the hook notified of loads, none if nil.
+func (c *Configuration) SetMetricsHook(hook MetricsHook) {
+	c.mu.Lock()
+	c.metricsHook = hook
+	c.mu.Unlock()
+}
+
+// observeLoad notifies the metrics hook of c of a load from source.
+func (c *Configuration) observeLoad(source string, noop bool) {
+	c.mu.RLock()
+	hook := c.metricsHook
+	c.mu.RUnlock()
+	if hook != nil {
+		hook(source, noop)
+	}
+}
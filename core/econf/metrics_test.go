@@ -0,0 +1,49 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNoopReload(t *testing.T) {
+	withOptions(t)
+	v := New()
+	type load struct {
+		source string
+		noop   bool
+	}
+	var loads []load
+	v.SetMetricsHook(func(source string, noop bool) {
+		loads = append(loads, load{source, noop})
+	})
+	fired := make(chan string, 4)
+	v.OnChange(func(c *Configuration) {
+		fired <- c.GetString("foo")
+	})
+	ds := newFakeDataSource(`foo = "bar"`)
+	defer ds.Close()
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true), WithSourceName("file")))
+	assert.Equal(t, "bar", <-fired)
+	version := v.version
+
+	// identical content is neither applied nor notified
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, version, v.version)
+	assert.Empty(t, fired)
+	assert.Equal(t, []load{{"file", false}, {"file", true}}, loads)
+
+	// identical content is applied again over an update since
+	assert.NoError(t, v.Set("foo", "set"))
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, "bar", v.GetString("foo"))
+	assert.Equal(t, "bar", <-fired)
+	assert.Equal(t, load{"file", false}, loads[len(loads)-1])
+
+	// or from another source
+	assert.NoError(t, v.Load([]byte(`foo = "bar"`), toml.Unmarshal))
+	assert.Equal(t, load{"", false}, loads[len(loads)-1])
+	assert.NoError(t, v.Load([]byte(`foo = "bar"`), toml.Unmarshal))
+	assert.Equal(t, load{"", true}, loads[len(loads)-1])
+}
@@ -0,0 +1,62 @@
+package econf
+
+// GetMulti returns the values of keys with default defaultConfiguration.
+func GetMulti(keys []string) []interface{} {
+	return defaultConfiguration.GetMulti(keys)
+}
+
+// GetMulti returns the value of each of keys as Get does, all read from the same state of the config:
+// an update committed meanwhile is seen by all of them or by none, unlike successive Gets.
+// Values are resolved from the config tree, since the key cache may be refreshed half way through keys.
+// Values of the fallback are cached as find caches them. Reads through an Overlay resolve each key
+// from its parent as Get does.
+func (c *Configuration) GetMulti(keys []string) []interface{} {
+	values := make([]interface{}, len(keys))
+	if c.parent != nil {
+		for i, key := range keys {
+			values[i] = c.find(key)
+		}
+		return values
+	}
+
+	disableCache := defaultContainer.DisableCache
+	// read the generation before the state, so a value read during a refresh is never cached after it
+	generation := c.generation.Load()
+	var (
+		override map[string]interface{}
+		fallback func(key string) (interface{}, bool)
+	)
+	if s := c.snapshot.Load(); s != nil {
+		override, fallback = s.override, s.fallback
+	} else {
+		c.mu.RLock()
+		override, fallback = c.override, c.fallback
+		defer c.mu.RUnlock()
+	}
+	inTree := func(key string) interface{} {
+		// lookupPath never modifies override, which readers share
+		dd, ok, _ := lookupPath(override, c.splitPath(key))
+		if ok || fallback == nil {
+			return dd
+		}
+		if !disableCache && generation%2 == 0 {
+			// a cached value is only as recent as the state read above while the generation is unchanged
+			if cached, ok := c.keyMap.Load(key); ok && c.generation.Load() == generation {
+				if defaultContainer.CacheSize > 0 {
+					c.lookups.touch(key)
+				}
+				return cached
+			}
+		}
+		dd, _ = fallback(key)
+		// skip caching during a refresh, since it may have set key
+		if !disableCache && generation%2 == 0 {
+			c.cacheLookup(key, dd, generation)
+		}
+		return dd
+	}
+	for i, key := range keys {
+		values[i] = c.resolve(key, inTree)
+	}
+	return values
+}
@@ -0,0 +1,115 @@
+package econf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMulti(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("server.host", "localhost"))
+	v.SetDefault("timeout", "1s")
+	assert.NoError(t, v.RegisterAlias("port", "server.port"))
+	v.RegisterFallback(func(key string) (interface{}, bool) {
+		if key == "remote" {
+			return "remote", true
+		}
+		return nil, false
+	})
+
+	keys := []string{"server.port", "port", "server", "timeout", "remote", "missing"}
+	expected := make([]interface{}, len(keys))
+	for i, key := range keys {
+		expected[i] = v.Get(key)
+	}
+	assert.Equal(t, expected, v.GetMulti(keys))
+	assert.Equal(t, []interface{}{80, "localhost", "1s", "remote", nil}, v.GetMulti([]string{"server.port", "server.host", "timeout", "remote", "missing"}))
+	assert.Empty(t, v.GetMulti(nil))
+
+	o := v.Overlay(map[string]interface{}{"server": map[string]interface{}{"port": 81}})
+	defer o.Release()
+	assert.Equal(t, []interface{}{81, "localhost"}, o.GetMulti([]string{"server.port", "server.host"}))
+}
+
+func TestGetMultiFallbackCached(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.NoError(t, v.Set("local", 1))
+	calls := map[string]int{}
+	v.RegisterFallback(func(key string) (interface{}, bool) {
+		calls[key]++
+		if key == "remote" {
+			return "remote", true
+		}
+		return nil, false
+	})
+
+	keys := []string{"local", "remote", "missing"}
+	assert.Equal(t, []interface{}{1, "remote", nil}, v.GetMulti(keys))
+	assert.Equal(t, []interface{}{1, "remote", nil}, v.GetMulti(keys))
+	assert.Equal(t, "remote", v.Get("remote"))
+	assert.Nil(t, v.Get("missing"))
+	assert.Equal(t, map[string]int{"remote": 1, "missing": 1}, calls)
+
+	t.Run("disabled cache", func(t *testing.T) {
+		withOptions(t, WithDisableCache(true))
+		v.GetMulti(keys)
+		assert.Equal(t, map[string]int{"remote": 2, "missing": 2}, calls)
+	})
+}
+
+func TestGetMultiConsistent(t *testing.T) {
+	withOptions(t)
+	v := New()
+	assert.NoError(t, v.Set("pair", map[string]interface{}{"a": 0, "b": 0}))
+	const updates = 200
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				values := v.GetMulti([]string{"pair.a", "pair.b"})
+				assert.Equal(t, values[0], values[1])
+			}
+		}
+	}()
+	for i := 1; i <= updates; i++ {
+		assert.NoError(t, v.Set("pair", map[string]interface{}{"a": i, "b": i}))
+	}
+	close(done)
+	wg.Wait()
+}
+
+// BenchmarkGetMulti compares successive Gets of keys with one GetMulti.
+func BenchmarkGetMulti(b *testing.B) {
+	v := New()
+	keys := make([]string, 8)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("service.k%d", i)
+		_ = v.Set(keys[i], i)
+	}
+	b.Run("Get", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, key := range keys {
+				_ = v.Get(key)
+			}
+		}
+	})
+	b.Run("GetMulti", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = v.GetMulti(keys)
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package econf
+
+import (
+	"time"
+
+	"github.com/spf13/cast"
+)
+
+// mustGet returns the value associated with the key converted by conv,
+// and panics with a *KeyError naming the key if it is missing or can't be converted.
+func mustGet[T any](c *Configuration, op string, key string, conv func(interface{}) (T, error)) T {
+	value := c.Get(key)
+	if value == nil {
+		panic(&KeyError{Key: key, Op: op, Err: ErrInvalidKey})
+	}
+	out, err := conv(value)
+	if err != nil {
+		panic(&KeyError{Key: key, Op: op, Err: err})
+	}
+	return out
+}
+
+// MustGetString returns the value associated with the key as a string with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetString(key string) string {
+	return defaultConfiguration.MustGetString(key)
+}
+
+// MustGetString returns the value associated with the key as a string,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetString(key string) string {
+	return c.expandEnvOf(key, mustGet(c, "MustGetString", key, cast.ToStringE))
+}
+
+// MustGetBool returns the value associated with the key as a boolean with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetBool(key string) bool {
+	return defaultConfiguration.MustGetBool(key)
+}
+
+// MustGetBool returns the value associated with the key as a boolean,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetBool(key string) bool {
+	return mustGet(c, "MustGetBool", key, cast.ToBoolE)
+}
+
+// MustGetInt returns the value associated with the key as an integer with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetInt(key string) int {
+	return defaultConfiguration.MustGetInt(key)
+}
+
+// MustGetInt returns the value associated with the key as an integer,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetInt(key string) int {
+	return mustGet(c, "MustGetInt", key, cast.ToIntE)
+}
+
+// MustGetInt64 returns the value associated with the key as an integer with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetInt64(key string) int64 {
+	return defaultConfiguration.MustGetInt64(key)
+}
+
+// MustGetInt64 returns the value associated with the key as an integer,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetInt64(key string) int64 {
+	return mustGet(c, "MustGetInt64", key, cast.ToInt64E)
+}
+
+// MustGetFloat64 returns the value associated with the key as a float64 with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetFloat64(key string) float64 {
+	return defaultConfiguration.MustGetFloat64(key)
+}
+
+// MustGetFloat64 returns the value associated with the key as a float64,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetFloat64(key string) float64 {
+	return mustGet(c, "MustGetFloat64", key, toLocaleFloat64E)
+}
+
+// MustGetDuration returns the value associated with the key as a duration with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetDuration(key string) time.Duration {
+	return defaultConfiguration.MustGetDuration(key)
+}
+
+// MustGetDuration returns the value associated with the key as a duration, which may be
+// a duration object as GetDuration accepts, and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetDuration(key string) time.Duration {
+	return mustGet(c, "MustGetDuration", key, func(value interface{}) (time.Duration, error) {
+		if d, ok := durationFromMap(value); ok {
+			return d, nil
+		}
+		return cast.ToDurationE(value)
+	})
+}
+
+// MustGetStringSlice returns the value associated with the key as a slice of strings with default defaultConfiguration,
+// and panics if the key is missing or can't be converted.
+func MustGetStringSlice(key string) []string {
+	return defaultConfiguration.MustGetStringSlice(key)
+}
+
+// MustGetStringSlice returns the value associated with the key as a slice of strings as GetStringSlice does,
+// and panics if the key is missing or can't be converted.
+func (c *Configuration) MustGetStringSlice(key string) []string {
+	mustGet(c, "MustGetStringSlice", key, cast.ToStringSliceE)
+	return c.GetStringSlice(key)
+}
@@ -0,0 +1,48 @@
+package econf
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMustGet(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("app.name", "ego"))
+	assert.NoError(t, v.Set("app.port", "80"))
+	assert.NoError(t, v.Set("app.debug", "true"))
+	assert.NoError(t, v.Set("app.ratio", 0.5))
+	assert.NoError(t, v.Set("app.timeout", "1s"))
+	assert.NoError(t, v.Set("app.idle", map[string]interface{}{"seconds": 30}))
+	assert.NoError(t, v.Set("app.hosts", []interface{}{"a", "b"}))
+	assert.NoError(t, v.Set("app.bad", "not-a-number"))
+
+	assert.Equal(t, "ego", v.MustGetString("app.name"))
+	assert.Equal(t, 80, v.MustGetInt("app.port"))
+	assert.Equal(t, int64(80), v.MustGetInt64("app.port"))
+	assert.True(t, v.MustGetBool("app.debug"))
+	assert.Equal(t, 0.5, v.MustGetFloat64("app.ratio"))
+	assert.Equal(t, time.Second, v.MustGetDuration("app.timeout"))
+	assert.Equal(t, 30*time.Second, v.MustGetDuration("app.idle"))
+	assert.Equal(t, []string{"a", "b"}, v.MustGetStringSlice("app.hosts"))
+
+	assertPanicsWithKey := func(key string, fn func()) {
+		t.Helper()
+		defer func() {
+			r := recover()
+			assert.NotNil(t, r, key)
+			assert.Contains(t, fmt.Sprint(r), key)
+		}()
+		fn()
+	}
+	assertPanicsWithKey("app.missing", func() { v.MustGetString("app.missing") })
+	assertPanicsWithKey("app.missing", func() { v.MustGetDuration("app.missing") })
+	assertPanicsWithKey("app.bad", func() { v.MustGetInt("app.bad") })
+	assertPanicsWithKey("app.bad", func() { v.MustGetInt64("app.bad") })
+	assertPanicsWithKey("app.bad", func() { v.MustGetBool("app.bad") })
+	assertPanicsWithKey("app.bad", func() { v.MustGetFloat64("app.bad") })
+	assertPanicsWithKey("app.bad", func() { v.MustGetDuration("app.bad") })
+	assertPanicsWithKey("app.idle", func() { v.MustGetString("app.idle") })
+}
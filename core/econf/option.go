@@ -1,5 +1,9 @@
 package econf
 
+import (
+	"github.com/mitchellh/mapstructure"
+)
+
 // Option is an optional argument to Container.
 type Option func(o *Container)
 
@@ -23,3 +27,271 @@ func WithSquash(squash bool) Option {
 		o.Squash = squash
 	}
 }
+
+// WithCSVSlices sets if a scalar string such as "a,b,c" should be split on commas
+// when read by GetStringSlice or decoded into a []string field.
+// Values that are already lists are not affected.
+func WithCSVSlices(enable bool) Option {
+	return func(o *Container) {
+		o.EnableCSVSlices = enable
+	}
+}
+
+// WithRefs sets if `${ref:other.key}` tokens in string values should be replaced
+// by the value of the referenced key when loading config.
+func WithRefs(enable bool) Option {
+	return func(o *Container) {
+		o.EnableRefs = enable
+	}
+}
+
+// WithSyncOnChange sets if the OnChange callbacks of the first load should run
+// before LoadFromDataSource returns, instead of in the watching goroutine.
+// Either way, callbacks of a load run one by one in priority order.
+func WithSyncOnChange(sync bool) Option {
+	return func(o *Container) {
+		o.SyncOnChange = sync
+	}
+}
+
+// WithMergeNullDeletes sets if a null value in a merged layer deletes the key,
+// instead of being ignored when the key already exists.
+func WithMergeNullDeletes(enable bool) Option {
+	return func(o *Container) {
+		o.MergeNullDeletes = enable
+	}
+}
+
+// WithEnvExpansion sets if `${VAR}` tokens in string values should be expanded from
+// the environment when read by GetString, GetStringSlice and GetStringMapString.
+func WithEnvExpansion(enable bool) Option {
+	return func(o *Container) {
+		o.EnableEnvExpansion = enable
+	}
+}
+
+// WithIgnoreExpansionKeys sets keys whose values, and those of the keys under them, are never env expanded,
+// e.g. shell scripts using `${VAR}` themselves. PEM-like values are never expanded either.
+func WithIgnoreExpansionKeys(keys []string) Option {
+	return func(o *Container) {
+		o.IgnoreExpansionKeys = keys
+	}
+}
+
+// WithRequiredEnv sets the environment variables Load, and every reload, requires, as RequireEnv checks them:
+// a load with any of them missing fails with a single error listing them all, and the config is kept.
+// With WithEnvOverride, an entry holding the key delimiter is a key, requiring its override variable,
+// e.g. `server.port` requires EGO_SERVER_PORT with prefix EGO.
+func WithRequiredEnv(vars []string) Option {
+	return func(o *Container) {
+		o.RequiredEnv = vars
+	}
+}
+
+// WithRequireExpandedEnv sets if Load should require the variables of the `${VAR}` tokens in the loaded
+// values which WithEnvExpansion expands, along with those of WithRequiredEnv, instead of expanding
+// the missing ones to empty strings on read.
+func WithRequireExpandedEnv(require bool) Option {
+	return func(o *Container) {
+		o.RequireExpandedEnv = require
+	}
+}
+
+// WithMetadata sets md to receive the keys decoded and the keys left unused by UnmarshalKey,
+// e.g. to reject unknown keys. Keys collected by a `mapstructure:",remain"` map field, which decodes
+// the keys matching no other field, are not reported as unused.
+// Pass it to a single UnmarshalKey, as concurrent decodes would share md.
+func WithMetadata(md *mapstructure.Metadata) Option {
+	return func(o *Container) {
+		o.Metadata = md
+	}
+}
+
+// WithDurationHook sets if UnmarshalKey should parse strings like "1s" into time.Duration fields. It's enabled
+// by default; disable it e.g. to decode them with a hook of WithDecodeHook instead.
+// The hook never applies to other fields, such as time.Time ones.
+func WithDurationHook(enable bool) Option {
+	return func(o *Container) {
+		o.DisableDurationHook = !enable
+	}
+}
+
+// WithRadixInts sets UnmarshalKey to parse strings like "0xFF", "0o644" or "0b101" into integer fields,
+// the base being detected from the prefix as GetIntAuto does. Without it, such strings fail to decode,
+// unless WithWeaklyTypedInput is set.
+func WithRadixInts(enable bool) Option {
+	return func(o *Container) {
+		o.EnableRadixInts = enable
+	}
+}
+
+// WithDecodeHook appends a mapstructure decode hook used by UnmarshalKey.
+func WithDecodeHook(hook mapstructure.DecodeHookFunc) Option {
+	return func(o *Container) {
+		// copy on append, the container may be a copy sharing the hooks of the default one
+		o.DecodeHooks = append(o.DecodeHooks[:len(o.DecodeHooks):len(o.DecodeHooks)], hook)
+	}
+}
+
+// WithEnum decodes string values into the enum type T by mapping in UnmarshalKey.
+// A token not in mapping fails the decoding with an error naming the field.
+func WithEnum[T any](mapping map[string]T) Option {
+	return WithDecodeHook(enumHookFunc(mapping))
+}
+
+// WithZeroFields sets if the target of UnmarshalKey should be reset before decoding,
+// so fields absent from config don't keep the values of a previous decoding.
+func WithZeroFields(zeroFields bool) Option {
+	return func(o *Container) {
+		o.ZeroFields = zeroFields
+	}
+}
+
+// WithMergeAppendKeys sets keys whose lists are appended to, instead of replaced, when merging layers.
+// A key also applies to the lists under it, e.g. `rules` appends to `rules.get` and `rules.post`.
+// A reload of a DataSource replaces the elements it appended before, instead of appending them again.
+func WithMergeAppendKeys(keys []string) Option {
+	return func(o *Container) {
+		o.MergeAppendKeys = keys
+	}
+}
+
+// WithMaxDepth sets the maximum nesting depth of maps flattened into keys, 64 by default.
+// Maps nested deeper are kept as a single value instead of being flattened further.
+func WithMaxDepth(depth int) Option {
+	return func(o *Container) {
+		o.MaxDepth = depth
+	}
+}
+
+// WithDisableCache sets if reads should always resolve from the config tree and never be cached.
+// It trades some read speed for reads that always reflect the config tree, e.g. while debugging the cache.
+func WithDisableCache(disable bool) Option {
+	return func(o *Container) {
+		o.DisableCache = disable
+	}
+}
+
+// WithTypedCache sets if the cast values of GetString, GetBool, GetInt, GetInt64 and GetFloat64
+// should be cached until the next update, for keys read on hot paths. The maps of GetStringMapString
+// are cached too, until the subtree they are read from is replaced.
+// It costs one cache entry per key read, replaced when the key is read as another type.
+func WithTypedCache(enable bool) Option {
+	return func(o *Container) {
+		o.EnableTypedCache = enable
+	}
+}
+
+// WithTreatEmptyAsUnset sets if empty string values should be treated as not set,
+// so IsSet returns false for them and the getters with a default return the default.
+func WithTreatEmptyAsUnset(treat bool) Option {
+	return func(o *Container) {
+		o.TreatEmptyAsUnset = treat
+	}
+}
+
+// WithCleanStringSlices sets if GetStringSlice should trim its elements and drop the empty ones,
+// as GetStringSliceClean does.
+func WithCleanStringSlices(clean bool) Option {
+	return func(o *Container) {
+		o.CleanStringSlices = clean
+	}
+}
+
+// WithDedupStringSlices sets if the slices of GetStringMapStringSlice, and of map[string][]string fields
+// decoded by UnmarshalKey, should drop duplicate elements, keeping the first of each in order.
+func WithDedupStringSlices(dedup bool) Option {
+	return func(o *Container) {
+		o.DedupStringSlices = dedup
+	}
+}
+
+// WithIndexedSlices sets if GetStringMapStringSlice should turn maps whose keys are all indexes,
+// e.g. from flattened `rules.get.0` and `rules.get.1` keys, into slices ordered by index.
+func WithIndexedSlices(enable bool) Option {
+	return func(o *Container) {
+		o.IndexedSlices = enable
+	}
+}
+
+// WithReadTracing sets if the reads of each key should be counted, as reported by ReadStats.
+// When disabled, tracing costs a single check per read.
+func WithReadTracing(enable bool) Option {
+	return func(o *Container) {
+		o.EnableReadTracing = enable
+	}
+}
+
+// WithEnvOverride sets the prefix of environment variables overriding keys when read, e.g. EGO_SERVER_PORT
+// overrides `server.port` with prefix EGO. Subtree reads such as GetStringMap include the overrides
+// of their leaves too. An empty prefix disables env overrides, which is the default.
+// The environment is read once per update, e.g. a Load, a reload or a Set, so a variable exported
+// in between applies from the next update.
+func WithEnvOverride(prefix string) Option {
+	return func(o *Container) {
+		o.EnvOverridePrefix = prefix
+	}
+}
+
+// WithNumberFormat sets the decimal separator the float getters, such as GetFloat64, GetFloat32 and GetPercent,
+// and UnmarshalKey expect in string values, e.g. `,` to parse "3,14" as 3.14. Native numbers are unaffected.
+// The default is `.`.
+func WithNumberFormat(decimalSep string) Option {
+	return func(o *Container) {
+		o.DecimalSeparator = decimalSep
+	}
+}
+
+// WithCacheSize bounds the number of keys cached by reads besides the leaves of config, e.g. misses of
+// per-tenant keys, evicting the least recently used ones. Leaves stay cached, as they are bounded by config.
+// A non-positive size, the default, leaves the cache unbounded. Bounding costs a lock on each cached read.
+func WithCacheSize(size int) Option {
+	return func(o *Container) {
+		o.CacheSize = size
+	}
+}
+
+// WithDecodeWorkers sets the number of keys UnmarshalKeys decodes in parallel, e.g. to decode many
+// large subtrees at startup. Keys are decoded sequentially by default.
+func WithDecodeWorkers(workers int) Option {
+	return func(o *Container) {
+		o.DecodeWorkers = workers
+	}
+}
+
+// WithInternKeys sets if the path segments of loaded and set config should be interned, so a segment
+// repeated across subtrees, such as the field names of many similar items, shares one copy.
+// It only saves the bytes of the repeated segments, e.g. for decoders like yaml.v3 which allocate each key,
+// at the cost of a lock per key when loading. Interned segments are kept for the life of the Configuration.
+func WithInternKeys(enable bool) Option {
+	return func(o *Container) {
+		o.InternKeys = enable
+	}
+}
+
+// WithDiscardRawConfig sets if Load should drop the content it loaded instead of keeping it for RawConfig,
+// so the bytes of a large config aren't retained. RawConfig returns nil then, which also empties
+// the config page of egovernor.
+func WithDiscardRawConfig(discard bool) Option {
+	return func(o *Container) {
+		o.DiscardRawConfig = discard
+	}
+}
+
+// WithDisableChangeDetection sets if updates should skip detecting the keys they change, e.g. for
+// deployments which never watch config after startup. Updates then only merge and refresh the cache:
+// OnChange, OnChangeNamed, Watch, WatchKey and WatchChan register callbacks which never fire.
+// It applies to the Configurations created by New, or loaded by LoadFromDataSource, after it is set.
+func WithDisableChangeDetection(disable bool) Option {
+	return func(o *Container) {
+		o.DisableChangeDetection = disable
+	}
+}
+
+// WithSourceName names the layer loaded by LoadFromDataSource, so SourceOf reports it for the keys it set.
+// Unlike other options, it only applies to the LoadFromDataSource it is passed to.
+func WithSourceName(name string) Option {
+	return func(o *Container) {
+		o.SourceName = name
+	}
+}
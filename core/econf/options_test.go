@@ -1,15 +1,22 @@
 package econf
 
 import (
+	"fmt"
 	"os"
 	"path"
+	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
+	"github.com/mitchellh/mapstructure"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestWithTagName(t *testing.T) {
+	withOptions(t)
 	watchDir := os.TempDir()
 	configFile := path.Join(watchDir, "config.toml")
 	err := os.WriteFile(configFile, []byte(`foo= "baz"`), 0640)
@@ -28,3 +35,383 @@ func TestWithTagName(t *testing.T) {
 	err2 := v.LoadFromDataSource(provider, toml.Unmarshal, WithSquash(true))
 	assert.NoError(t, err2)
 }
+
+func TestWithZeroFields(t *testing.T) {
+	type config struct {
+		Host   string
+		Port   int
+		Labels map[string]string
+	}
+	v := New()
+	assert.NoError(t, v.Set("host", "example.com"))
+	assert.NoError(t, v.Set("port", 80))
+	assert.NoError(t, v.Set("labels", map[string]interface{}{"a": "1"}))
+	var out config
+	assert.NoError(t, v.UnmarshalKey("", &out))
+	assert.Equal(t, "example.com", out.Host)
+
+	// the config without host and with other labels
+	v = New()
+	assert.NoError(t, v.Load([]byte("port = 81\n[labels]\nb = \"2\"\n"), toml.Unmarshal))
+
+	stale := out
+	assert.NoError(t, v.UnmarshalKey("", &stale))
+	assert.Equal(t, "example.com", stale.Host)
+	assert.Equal(t, map[string]string{"a": "1", "b": "2"}, stale.Labels)
+
+	assert.NoError(t, v.UnmarshalKey("", &out, WithZeroFields(true)))
+	assert.Equal(t, config{Port: 81, Labels: map[string]string{"b": "2"}}, out)
+}
+
+func TestWithDisableCache(t *testing.T) {
+	read := func() map[string]interface{} {
+		v := New()
+		assert.NoError(t, v.Set("server", map[string]interface{}{"port": 80}))
+		assert.Equal(t, map[string]interface{}{"port": 80}, v.GetStringMap("server"))
+		assert.NoError(t, v.Set("server", map[string]interface{}{"port": 81}))
+		return v.GetStringMap("server")
+	}
+
+	// the value of a fallback is cached like any other, until the next update
+	fallbackCalls := func() int {
+		v := New()
+		calls := 0
+		v.RegisterFallback(func(key string) (interface{}, bool) {
+			calls++
+			return calls, true
+		})
+		v.GetInt("missing")
+		v.GetInt("missing")
+		return calls
+	}
+
+	t.Run("default", func(t *testing.T) {
+		withOptions(t)
+		assert.Equal(t, map[string]interface{}{"port": 81}, read())
+		assert.Equal(t, 1, fallbackCalls())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		withOptions(t, WithDisableCache(true))
+		assert.Equal(t, map[string]interface{}{"port": 81}, read())
+		// every read resolves from the tree, so the fallback again
+		assert.Equal(t, 2, fallbackCalls())
+
+		v := New()
+		assert.NoError(t, v.Set("a.b", 1))
+		assert.Equal(t, 1, v.GetInt("a.b"))
+		assert.NoError(t, v.Set("a.b", 2))
+		assert.Equal(t, 2, v.GetInt("a.b"))
+	})
+}
+
+func TestWithTreatEmptyAsUnset(t *testing.T) {
+	newConf := func() *Configuration {
+		v := New()
+		assert.NoError(t, v.Set("timeout", ""))
+		assert.NoError(t, v.Set("dsn", ""))
+		assert.NoError(t, v.Set("name", "ego"))
+		return v
+	}
+
+	t.Run("default", func(t *testing.T) {
+		v := newConf()
+		assert.True(t, v.IsSet("timeout"))
+		assert.Equal(t, time.Duration(0), v.GetDurationWithDefault("timeout", time.Second))
+		assert.Equal(t, "", v.GetStringChain("dsn", "", "localhost"))
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		withOptions(t, WithTreatEmptyAsUnset(true))
+		v := newConf()
+		assert.False(t, v.IsSet("timeout"))
+		assert.Equal(t, time.Second, v.GetDurationWithDefault("timeout", time.Second))
+		assert.Equal(t, "localhost", v.GetStringChain("dsn", "", "localhost"))
+		assert.True(t, v.IsSet("name"))
+		assert.Equal(t, "ego", v.GetStringChain("name", "", "localhost"))
+	})
+}
+
+func TestWithCleanStringSlices(t *testing.T) {
+	newConf := func() *Configuration {
+		v := New()
+		assert.NoError(t, v.Set("hosts", []interface{}{" a ", "", "b", "  ", "\tc\n"}))
+		assert.NoError(t, v.Set("csv", " a, ,b ,,"))
+		return v
+	}
+
+	t.Run("raw", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true))
+		v := newConf()
+		assert.Equal(t, []string{" a ", "", "b", "  ", "\tc\n"}, v.GetStringSlice("hosts"))
+		assert.Equal(t, []string{"a", "", "b", "", ""}, v.GetStringSlice("csv"))
+		assert.Equal(t, []string{"a", "b", "c"}, v.GetStringSliceClean("hosts"))
+		assert.Equal(t, []string{"a", "b"}, v.GetStringSliceClean("csv"))
+		assert.Equal(t, []string{}, v.GetStringSliceClean("missing"))
+	})
+
+	t.Run("clean", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true), WithCleanStringSlices(true))
+		v := newConf()
+		assert.Equal(t, []string{"a", "b", "c"}, v.GetStringSlice("hosts"))
+		assert.Equal(t, []string{"a", "b"}, v.GetStringSlice("csv"))
+	})
+}
+
+func TestWithDedupStringSlices(t *testing.T) {
+	newConf := func() *Configuration {
+		v := New()
+		assert.NoError(t, v.Set("routes./api", []interface{}{"X-Token", "X-Trace", "X-Token", "Accept", "X-Trace"}))
+		assert.NoError(t, v.Set("routes./health", "Accept, Accept"))
+		return v
+	}
+	type config struct {
+		Routes map[string][]string
+	}
+
+	t.Run("default", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true))
+		v := newConf()
+		assert.Equal(t, []string{"X-Token", "X-Trace", "X-Token", "Accept", "X-Trace"}, v.GetStringMapStringSlice("routes")["/api"])
+		var out config
+		assert.NoError(t, v.UnmarshalKey("", &out))
+		assert.Equal(t, []string{"X-Token", "X-Trace", "X-Token", "Accept", "X-Trace"}, out.Routes["/api"])
+	})
+
+	t.Run("dedup", func(t *testing.T) {
+		withOptions(t, WithCSVSlices(true), WithDedupStringSlices(true))
+		v := newConf()
+		expected := map[string][]string{
+			"/api":    {"X-Token", "X-Trace", "Accept"},
+			"/health": {"Accept"},
+		}
+		assert.Equal(t, expected, v.GetStringMapStringSlice("routes"))
+		var out config
+		assert.NoError(t, v.UnmarshalKey("", &out))
+		assert.Equal(t, expected, out.Routes)
+	})
+}
+
+func TestWithIndexedSlices(t *testing.T) {
+	newConf := func() *Configuration {
+		v := New()
+		assert.NoError(t, v.Set("rules.get.1", "/b"))
+		assert.NoError(t, v.Set("rules.get.0", "/a"))
+		assert.NoError(t, v.Set("rules.get.10", "/c"))
+		assert.NoError(t, v.Set("rules.post", []interface{}{"/d"}))
+		assert.NoError(t, v.Set("rules.put.x", "/e"))
+		return v
+	}
+
+	t.Run("disabled", func(t *testing.T) {
+		v := newConf()
+		m := v.GetStringMapStringSlice("rules")
+		assert.NotEqual(t, []string{"/a", "/b", "/c"}, m["get"])
+		assert.Equal(t, []string{"/d"}, m["post"])
+	})
+
+	t.Run("enabled", func(t *testing.T) {
+		withOptions(t, WithIndexedSlices(true))
+		v := newConf()
+		m := v.GetStringMapStringSlice("rules")
+		assert.Equal(t, []string{"/a", "/b", "/c"}, m["get"])
+		assert.Equal(t, []string{"/d"}, m["post"])
+		_, ok := indexedSlice(v.Get("rules.put"))
+		assert.False(t, ok)
+		// the stored map is untouched
+		assert.Equal(t, "/a", v.Get("rules.get.0"))
+	})
+}
+
+func TestWithCacheSize(t *testing.T) {
+	withOptions(t, WithCacheSize(100))
+	v := New()
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("tenants.t1.name", "first"))
+
+	cached := func() int {
+		n := 0
+		v.keyMap.Range(func(_, _ interface{}) bool {
+			n++
+			return true
+		})
+		return n
+	}
+	for i := 0; i < 10000; i++ {
+		assert.Nil(t, v.Get(fmt.Sprintf("tenants.t%d.missing", i)))
+	}
+	// the two leaves plus the bounded lookups
+	assert.Equal(t, 102, cached())
+	assert.Equal(t, 100, v.lookups.len())
+	assert.Equal(t, 80, v.GetInt("server.port"))
+
+	// the most recently used lookups are kept
+	_, ok := v.keyMap.Load("tenants.t9999.missing")
+	assert.True(t, ok)
+	_, ok = v.keyMap.Load("tenants.t0.missing")
+	assert.False(t, ok)
+
+	// cached misses are invalidated by updates
+	assert.NoError(t, v.Set("tenants.t9999.missing", "found"))
+	assert.Equal(t, "found", v.GetString("tenants.t9999.missing"))
+	assert.Equal(t, map[string]interface{}{"name": "first"}, v.GetStringMap("tenants.t1"))
+	assert.NoError(t, v.Set("tenants.t1.name", "renamed"))
+	assert.Equal(t, map[string]interface{}{"name": "renamed"}, v.GetStringMap("tenants.t1"))
+	assert.LessOrEqual(t, v.lookups.len(), 100)
+}
+
+func TestWithDiscardRawConfig(t *testing.T) {
+	content := []byte(`port = 80`)
+	t.Run("default", func(t *testing.T) {
+		withOptions(t)
+		v := New()
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Equal(t, content, v.raw())
+	})
+
+	t.Run("discard", func(t *testing.T) {
+		withOptions(t, WithDiscardRawConfig(true))
+		v := New()
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Nil(t, v.raw())
+		assert.Equal(t, int64(80), v.Get("port"))
+
+		// the content is garbage collected once the caller drops it
+		large := make([]byte, 1<<20)
+		copy(large, `port = 81`)
+		for i := len(`port = 81`); i < len(large); i++ {
+			large[i] = ' '
+		}
+		collected := make(chan struct{})
+		runtime.SetFinalizer(&large[0], func(*byte) { close(collected) })
+		assert.NoError(t, v.Load(large, toml.Unmarshal))
+		large = nil
+		assert.Eventually(t, func() bool {
+			runtime.GC()
+			select {
+			case <-collected:
+				return true
+			default:
+				return false
+			}
+		}, time.Second, 10*time.Millisecond)
+		assert.Equal(t, int64(81), v.Get("port"))
+	})
+}
+
+func TestWithDisableChangeDetection(t *testing.T) {
+	withOptions(t, WithDisableChangeDetection(true))
+	v := New()
+	fired := make(chan string, 4)
+	v.OnChange(func(*Configuration) { fired <- "change" })
+	v.Watch("server", func(*Configuration) { fired <- "watch" })
+	v.WatchKey("server.port", func(*Configuration) { fired <- "key" })
+	ds := newFakeDataSource("[server]\nport = 80\n")
+	defer ds.Close()
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+	ds.set("[server]\nport = 81\n")
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, 81, v.GetInt("server.port"))
+	assert.NoError(t, v.Set("server.port", 82))
+	assert.Equal(t, 82, v.GetInt("server.port"))
+	assert.NoError(t, v.Set("server", map[string]interface{}{"port": 83}))
+	assert.Equal(t, map[string]interface{}{"port": 83}, v.GetStringMap("server"))
+	// callbacks are dispatched in order, so none is pending once this one ran
+	drained := make(chan struct{})
+	v.watchDispatcher.dispatch(func() { close(drained) })
+	<-drained
+	assert.Empty(t, fired)
+}
+
+// BenchmarkSetChangeDetection compares a Set with and without change detection.
+func BenchmarkSetChangeDetection(b *testing.B) {
+	tree := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		tree[fmt.Sprintf("k%d", i)] = map[string]interface{}{"value": i}
+	}
+	for _, disable := range []bool{false, true} {
+		b.Run(fmt.Sprintf("disabled=%t", disable), func(b *testing.B) {
+			withOptions(b, WithDisableChangeDetection(disable))
+			v := New()
+			v.WatchKey("tree.k0.value", func(*Configuration) {})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = v.Set("tree", tree)
+				_ = v.Set("tree.k0.value", i)
+			}
+		})
+	}
+}
+
+func TestWithMetadata(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+[service]
+name = "api"
+port = 80
+env = "prod"
+team = "infra"
+`), toml.Unmarshal))
+
+	type service struct {
+		Name   string
+		Port   int
+		Labels map[string]string `mapstructure:",remain"`
+	}
+	var out service
+	var md mapstructure.Metadata
+	assert.NoError(t, v.UnmarshalKey("service", &out, WithMetadata(&md)))
+	assert.Equal(t, service{Name: "api", Port: 80, Labels: map[string]string{"env": "prod", "team": "infra"}}, out)
+	assert.Empty(t, md.Unused)
+	assert.Contains(t, md.Keys, "Name")
+
+	type strict struct {
+		Name string
+	}
+	var named strict
+	md = mapstructure.Metadata{}
+	assert.NoError(t, v.UnmarshalKey("service", &named, WithMetadata(&md)))
+	assert.ElementsMatch(t, []string{"port", "env", "team"}, md.Unused)
+	// the option only applies to the decode it is passed to
+	assert.Nil(t, GetOptionMetadata())
+}
+
+func TestWithDurationHook(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+[job]
+start = "2024-01-02T03:04:05Z"
+timeout = "1m30s"
+`), toml.Unmarshal))
+
+	type job struct {
+		Start   time.Time
+		Timeout time.Duration
+	}
+	var out job
+	assert.NoError(t, v.UnmarshalKey("job", &out))
+	assert.Equal(t, job{Start: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Timeout: 90 * time.Second}, out)
+
+	// a custom hook reading seconds as milliseconds replaces the duration hook, time.Time fields are unaffected
+	parseDuration := func(f reflect.Type, t reflect.Type, data interface{}) (interface{}, error) {
+		if t != reflect.TypeOf(time.Duration(0)) || f.Kind() != reflect.String {
+			return data, nil
+		}
+		return time.ParseDuration(strings.TrimSuffix(data.(string), "s") + "ms")
+	}
+	out = job{}
+	assert.NoError(t, v.UnmarshalKey("job", &out, WithDurationHook(false), WithDecodeHook(parseDuration)))
+	assert.Equal(t, job{Start: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Timeout: time.Minute + 30*time.Millisecond}, out)
+
+	out = job{}
+	assert.Error(t, v.UnmarshalKey("job", &out, WithDurationHook(false)))
+	assert.False(t, GetOptionDisableDurationHook())
+
+	// the hook disabled on the default container is enabled again by a later option
+	withOptions(t, WithDurationHook(false))
+	assert.True(t, GetOptionDisableDurationHook())
+	out = job{}
+	assert.Error(t, v.UnmarshalKey("job", &out))
+	assert.NoError(t, v.UnmarshalKey("job", &out, WithDurationHook(true)))
+	assert.Equal(t, 90*time.Second, out.Timeout)
+}
@@ -0,0 +1,97 @@
+package econf
+
+import (
+	"sync"
+
+	"github.com/spf13/cast"
+)
+
+var overlayPool = sync.Pool{
+	New: func() interface{} {
+		return &Configuration{keyMap: &sync.Map{}}
+	},
+}
+
+// Overlay returns a view of this instance with overrides layered over it, e.g. the config of
+// a tenant for the duration of a request. Unlike Clone, the parent is not copied: reads of keys
+// missing from overrides resolve from the parent, subtrees set on both sides are merged on read,
+// and updates of the parent are seen through the view. Reads through the view are not cached.
+// Call Release once done with the view to reuse it.
+func (c *Configuration) Overlay(overrides map[string]interface{}) *Configuration {
+	o := overlayPool.Get().(*Configuration)
+	o.override = deepCopyMap(overrides)
+	o.keyDelim = c.keyDelim
+	o.parent = c
+	return o
+}
+
+// Release returns an Overlay to the pool, it must not be used afterwards.
+// It does nothing for a Configuration that isn't an Overlay.
+func (c *Configuration) Release() {
+	if c.parent == nil {
+		return
+	}
+	keyMap := c.keyMap
+	keyMap.Range(func(k, _ interface{}) bool {
+		keyMap.Delete(k)
+		return true
+	})
+	// reset every field, so nothing registered on the view, such as aliases or secret keys,
+	// carries over to the next Overlay
+	*c = Configuration{keyMap: keyMap}
+	overlayPool.Put(c)
+}
+
+// findOverlay resolves key from the overrides of an Overlay, then from its parent.
+func (c *Configuration) findOverlay(key string) interface{} {
+	// the aliases of the parent apply to the overrides too
+	key = c.parent.canonicalKey(key)
+	// overlays are short-lived, so they share the path cache of their parent
+	paths := c.parent.splitPath(key)
+	c.mu.RLock()
+	value, ok, shadowed := lookupPath(c.override, paths)
+	c.mu.RUnlock()
+	if shadowed {
+		// an ancestor of key is overridden with a scalar
+		return nil
+	}
+	if ok {
+		if _, isMap := value.(map[string]interface{}); !isMap {
+			return value
+		}
+	}
+
+	parentValue := c.parent.find(key)
+	if !ok {
+		return parentValue
+	}
+	parentMap, isMap := parentValue.(map[string]interface{})
+	if !isMap {
+		return value
+	}
+	merged := deepCopyMap(parentMap)
+	overlayMap(merged, deepCopyMap(value.(map[string]interface{})))
+	return merged
+}
+
+// lookupPath returns the value at paths of m, without modifying m.
+// shadowed reports if an ancestor of paths is set to a value other than a map.
+func lookupPath(m map[string]interface{}, paths []string) (value interface{}, ok bool, shadowed bool) {
+	for _, k := range paths[:len(paths)-1] {
+		v, exists := m[k]
+		if !exists {
+			return nil, false, false
+		}
+		// cast.ToStringMapE allocates even for a map[string]interface{}
+		next, isMap := v.(map[string]interface{})
+		if !isMap {
+			var err error
+			if next, err = cast.ToStringMapE(v); err != nil {
+				return nil, false, true
+			}
+		}
+		m = next
+	}
+	value, ok = m[paths[len(paths)-1]]
+	return value, ok, false
+}
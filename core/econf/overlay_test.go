@@ -0,0 +1,114 @@
+package econf
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOverlay(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("db.dsn", "global"))
+	assert.NoError(t, v.Set("db.pool.max", 10))
+	assert.NoError(t, v.Set("db.pool.idle", 2))
+	assert.NoError(t, v.Set("cache.addr", "redis:6379"))
+	assert.NoError(t, v.Set("log.level", "info"))
+
+	o := v.Overlay(map[string]interface{}{
+		"db": map[string]interface{}{
+			"dsn":  "tenant-a",
+			"pool": map[string]interface{}{"max": 20},
+		},
+		"cache": "disabled",
+	})
+
+	// overrides first, then the parent
+	assert.Equal(t, "tenant-a", o.GetString("db.dsn"))
+	assert.Equal(t, 20, o.GetInt("db.pool.max"))
+	assert.Equal(t, 2, o.GetInt("db.pool.idle"))
+	assert.Equal(t, "info", o.GetString("log.level"))
+	assert.Equal(t, map[string]interface{}{"max": 20, "idle": 2}, o.GetStringMap("db.pool"))
+	// a scalar override shadows the parent subtree
+	assert.Equal(t, "disabled", o.GetString("cache"))
+	assert.Nil(t, o.Get("cache.addr"))
+
+	// updates of the parent are seen, the parent never sees the overrides
+	assert.NoError(t, v.Set("log.level", "debug"))
+	assert.Equal(t, "debug", o.GetString("log.level"))
+	assert.Equal(t, "global", v.GetString("db.dsn"))
+	assert.Equal(t, "redis:6379", v.GetString("cache.addr"))
+
+	// a released overlay is reused without leaking its overrides
+	o.Release()
+	o2 := v.Overlay(nil)
+	defer o2.Release()
+	assert.Equal(t, "global", o2.GetString("db.dsn"))
+	assert.Equal(t, 10, o2.GetInt("db.pool.max"))
+
+	// Release of a Configuration that isn't an overlay does nothing
+	v.Release()
+	assert.Equal(t, "global", v.GetString("db.dsn"))
+}
+
+func TestOverlayReleaseResets(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("secret", "global"))
+	overrides := map[string]interface{}{"secret": "tenant"}
+	// the pool may or may not reuse a released overlay, so release several
+	for i := 0; i < 10; i++ {
+		o := v.Overlay(overrides)
+		assert.Nil(t, o.Get("tenantalias"))
+		assert.Nil(t, o.Get("tenant.limit"))
+		assert.Contains(t, o.String(), "tenant")
+		assert.NotContains(t, o.String(), redactedValue)
+
+		assert.NoError(t, o.RegisterAlias("tenantalias", "secret"))
+		o.SetDefault("tenant.limit", 10)
+		o.RegisterSecretKey("secret")
+		assert.Equal(t, "tenant", o.Get("tenantalias"))
+		assert.Equal(t, 10, o.Get("tenant.limit"))
+		assert.Contains(t, o.String(), redactedValue)
+		o.Release()
+	}
+}
+
+func TestOverlayParentAlias(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.address", "x"))
+	assert.NoError(t, v.RegisterAlias("server.addr", "server.address"))
+	o := v.Overlay(map[string]interface{}{"server": map[string]interface{}{"address": "tenant"}})
+	defer o.Release()
+	assert.Equal(t, "tenant", o.Get("server.address"))
+	assert.Equal(t, "tenant", o.Get("server.addr"))
+	assert.Equal(t, "tenant", o.GetString("server.addr"))
+	assert.Equal(t, "x", v.Get("server.addr"))
+}
+
+func BenchmarkOverlay(b *testing.B) {
+	v := New()
+	for i := 0; i < 100; i++ {
+		_ = v.Set(fmt.Sprintf("tenants.t%d.key", i), i)
+	}
+	_ = v.Set("db.dsn", "global")
+	overrides := map[string]interface{}{"db": map[string]interface{}{"dsn": "tenant"}}
+
+	b.Run("Overlay", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			o := v.Overlay(overrides)
+			_ = o.GetString("db.dsn")
+			_ = o.GetInt("tenants.t1.key")
+			o.Release()
+		}
+	})
+	b.Run("Clone", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			o := v.Clone()
+			_ = o.Set("db.dsn", "tenant")
+			_ = o.GetString("db.dsn")
+			_ = o.GetInt("tenants.t1.key")
+		}
+	})
+}
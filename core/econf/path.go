@@ -0,0 +1,233 @@
+package econf
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+// ErrInvalidPath defines an error that the path of GetByPath can't be parsed.
+var ErrInvalidPath = errors.New("invalid path")
+
+type pathStepKind int
+
+const (
+	pathChild pathStepKind = iota
+	pathIndex
+	pathWildcard
+	pathFilter
+)
+
+// pathStep is a parsed step of a GetByPath path.
+type pathStep struct {
+	kind  pathStepKind
+	name  string
+	index int
+	// field and value of a `[?(@.field==value)]` filter
+	field []string
+	value string
+}
+
+// GetByPath returns the values matched by a JSONPath-like path with default defaultConfiguration.
+func GetByPath(path string) ([]interface{}, error) {
+	return defaultConfiguration.GetByPath(path)
+}
+
+// GetByPath returns the values matched by a JSONPath-like path, in document order.
+// The supported grammar is a subset of JSONPath:
+//
+//	$                 the root, every path starts with it
+//	.name or ['name'] a child of a map
+//	[n]               the n-th (0-based) element of a list
+//	.* or [*]         every element of a list, or every value of a map sorted by key
+//	[?(@.f=="v")]     every element of a list, or value of a map, whose field f equals v;
+//	                  f may be dotted, v may be a quoted string or a bare number or bool
+//
+// For example, `$.servers[?(@.zone=="us")].host` returns the hosts of all servers in zone us.
+// A path that matches nothing returns an empty slice and no error.
+func (c *Configuration) GetByPath(path string) ([]interface{}, error) {
+	steps, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	nodes := []interface{}{c.override}
+	for _, step := range steps {
+		var next []interface{}
+		for _, node := range nodes {
+			next = append(next, step.match(node)...)
+		}
+		nodes = next
+	}
+	if nodes == nil {
+		return []interface{}{}, nil
+	}
+	return nodes, nil
+}
+
+func (s pathStep) match(node interface{}) []interface{} {
+	switch s.kind {
+	case pathChild:
+		m, err := cast.ToStringMapE(node)
+		if err != nil {
+			return nil
+		}
+		if v, ok := m[s.name]; ok {
+			return []interface{}{v}
+		}
+	case pathIndex:
+		if list, ok := pathChildren(node, false); ok && s.index < len(list) {
+			return []interface{}{list[s.index]}
+		}
+	case pathWildcard:
+		list, _ := pathChildren(node, true)
+		return list
+	case pathFilter:
+		list, _ := pathChildren(node, true)
+		var out []interface{}
+		for _, e := range list {
+			if v, ok := pathField(e, s.field); ok && cast.ToString(v) == s.value {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// pathChildren returns the elements of a list, or the values of a map sorted by key if withMap.
+func pathChildren(node interface{}, withMap bool) ([]interface{}, bool) {
+	rv := reflect.ValueOf(node)
+	if rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array {
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = rv.Index(i).Interface()
+		}
+		return out, true
+	}
+	if !withMap {
+		return nil, false
+	}
+	m, err := cast.ToStringMapE(node)
+	if err != nil {
+		return nil, false
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	out := make([]interface{}, len(keys))
+	for i, k := range keys {
+		out[i] = m[k]
+	}
+	return out, true
+}
+
+// pathField returns the value of the dotted field in node.
+func pathField(node interface{}, field []string) (interface{}, bool) {
+	for _, name := range field {
+		m, err := cast.ToStringMapE(node)
+		if err != nil {
+			return nil, false
+		}
+		v, ok := m[name]
+		if !ok {
+			return nil, false
+		}
+		node = v
+	}
+	return node, true
+}
+
+func parsePath(path string) ([]pathStep, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("%s must start with $, err: %w", path, ErrInvalidPath)
+	}
+	var steps []pathStep
+	rest := path[1:]
+	for rest != "" {
+		var (
+			step pathStep
+			err  error
+		)
+		switch rest[0] {
+		case '.':
+			step, rest, err = parseDotStep(rest[1:])
+		case '[':
+			step, rest, err = parseBracketStep(rest[1:])
+		default:
+			err = fmt.Errorf("unexpected %q", rest[0])
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s, %v, err: %w", path, err, ErrInvalidPath)
+		}
+		steps = append(steps, step)
+	}
+	return steps, nil
+}
+
+func parseDotStep(rest string) (pathStep, string, error) {
+	if strings.HasPrefix(rest, "*") {
+		return pathStep{kind: pathWildcard}, rest[1:], nil
+	}
+	end := strings.IndexAny(rest, ".[")
+	if end < 0 {
+		end = len(rest)
+	}
+	if end == 0 {
+		return pathStep{}, "", errors.New("empty name")
+	}
+	return pathStep{kind: pathChild, name: rest[:end]}, rest[end:], nil
+}
+
+func parseBracketStep(rest string) (pathStep, string, error) {
+	switch {
+	case strings.HasPrefix(rest, "*]"):
+		return pathStep{kind: pathWildcard}, rest[2:], nil
+	case strings.HasPrefix(rest, "?("):
+		end := strings.Index(rest, ")]")
+		if end < 0 {
+			return pathStep{}, "", errors.New("unclosed filter")
+		}
+		step, err := parseFilter(rest[2:end])
+		return step, rest[end+2:], err
+	case strings.HasPrefix(rest, "'") || strings.HasPrefix(rest, `"`):
+		end := strings.Index(rest[1:], string(rest[0])+"]")
+		if end < 0 {
+			return pathStep{}, "", errors.New("unclosed name")
+		}
+		return pathStep{kind: pathChild, name: rest[1 : end+1]}, rest[end+3:], nil
+	default:
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			return pathStep{}, "", errors.New("unclosed index")
+		}
+		index, err := strconv.Atoi(rest[:end])
+		if err != nil || index < 0 {
+			return pathStep{}, "", fmt.Errorf("invalid index %q", rest[:end])
+		}
+		return pathStep{kind: pathIndex, index: index}, rest[end+1:], nil
+	}
+}
+
+// parseFilter parses `@.field==value`.
+func parseFilter(expr string) (pathStep, error) {
+	parts := strings.SplitN(expr, "==", 2)
+	field := strings.TrimSpace(parts[0])
+	if len(parts) != 2 || !strings.HasPrefix(field, "@.") || len(field) == 2 {
+		return pathStep{}, fmt.Errorf("unsupported filter %q", expr)
+	}
+	value := strings.TrimSpace(parts[1])
+	if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+		value = value[1 : len(value)-1]
+	}
+	return pathStep{kind: pathFilter, field: strings.Split(field[2:], "."), value: value}, nil
+}
@@ -0,0 +1,45 @@
+package econf
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetByPath(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`{
+		"servers": [
+			{"host": "a.example.com", "zone": "us", "port": 80},
+			{"host": "b.example.com", "zone": "eu", "port": 81},
+			{"host": "c.example.com", "zone": "us", "port": 82}
+		],
+		"db": {"main": {"dsn": "main-dsn"}, "backup": {"dsn": "backup-dsn"}}
+	}`), json.Unmarshal))
+
+	cases := []struct {
+		path   string
+		expect []interface{}
+	}{
+		{path: "$.servers[1].host", expect: []interface{}{"b.example.com"}},
+		{path: "$.servers[*].zone", expect: []interface{}{"us", "eu", "us"}},
+		{path: "$.db.*.dsn", expect: []interface{}{"backup-dsn", "main-dsn"}},
+		{path: "$['db']['main'].dsn", expect: []interface{}{"main-dsn"}},
+		{path: `$.servers[?(@.zone=="us")].host`, expect: []interface{}{"a.example.com", "c.example.com"}},
+		{path: `$.servers[?(@.port==81)].host`, expect: []interface{}{"b.example.com"}},
+		{path: "$.servers[5].host", expect: []interface{}{}},
+		{path: "$.missing", expect: []interface{}{}},
+	}
+	for _, tc := range cases {
+		out, err := v.GetByPath(tc.path)
+		assert.NoError(t, err, tc.path)
+		assert.Equal(t, tc.expect, out, tc.path)
+	}
+
+	for _, path := range []string{"servers", "$.servers[x]", "$.servers[?(@.zone)]", "$..host"} {
+		_, err := v.GetByPath(path)
+		assert.True(t, errors.Is(err, ErrInvalidPath), path)
+	}
+}
@@ -0,0 +1,50 @@
+package econf
+
+// prefixIndex is a byte trie of the prefixes registered by Watch, so the prefixes matching a changed key
+// are found in O(len(key)) rather than by testing each prefix. The zero value is ready to use.
+type prefixIndex struct {
+	root prefixNode
+}
+
+type prefixNode struct {
+	children map[byte]*prefixNode
+	// terminal reports if a registered prefix ends at this node
+	terminal bool
+	prefix   string
+}
+
+// insert registers prefix.
+func (idx *prefixIndex) insert(prefix string) {
+	node := &idx.root
+	for i := 0; i < len(prefix); i++ {
+		child, ok := node.children[prefix[i]]
+		if !ok {
+			if node.children == nil {
+				node.children = make(map[byte]*prefixNode)
+			}
+			child = &prefixNode{}
+			node.children[prefix[i]] = child
+		}
+		node = child
+	}
+	node.terminal = true
+	node.prefix = prefix
+}
+
+// match calls fn with each registered prefix of key, shortest first.
+func (idx *prefixIndex) match(key string, fn func(prefix string)) {
+	node := &idx.root
+	for i := 0; ; i++ {
+		if node.terminal {
+			fn(node.prefix)
+		}
+		if i == len(key) {
+			return
+		}
+		next, ok := node.children[key[i]]
+		if !ok {
+			return
+		}
+		node = next
+	}
+}
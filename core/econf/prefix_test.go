@@ -0,0 +1,103 @@
+package econf
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// naiveMatch returns the prefixes matching any of keys by testing each of them, as notifyChanges used to.
+func naiveMatch(prefixes []string, keys []string) []string {
+	matched := map[string]struct{}{}
+	for _, prefix := range prefixes {
+		for _, key := range keys {
+			if strings.HasPrefix(key, prefix) {
+				matched[prefix] = struct{}{}
+			}
+		}
+	}
+	return sortedKeys(matched)
+}
+
+func indexMatch(idx *prefixIndex, keys []string) []string {
+	matched := map[string]struct{}{}
+	for _, key := range keys {
+		idx.match(key, func(prefix string) {
+			matched[prefix] = struct{}{}
+		})
+	}
+	return sortedKeys(matched)
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func TestPrefixIndex(t *testing.T) {
+	var idx prefixIndex
+	prefixes := []string{"", "log", "log.level", "server.port", "server.portal", "mysql."}
+	for _, prefix := range prefixes {
+		idx.insert(prefix)
+	}
+	var got []string
+	idx.match("log.levels", func(prefix string) { got = append(got, prefix) })
+	assert.Equal(t, []string{"", "log", "log.level"}, got)
+
+	segments := []string{"a", "b", "ab", "server", "port", "log", "level"}
+	random := func(r *rand.Rand) string {
+		parts := make([]string, 1+r.Intn(3))
+		for i := range parts {
+			parts[i] = segments[r.Intn(len(segments))]
+		}
+		s := strings.Join(parts, ".")
+		return s[:r.Intn(len(s)+1)]
+	}
+	r := rand.New(rand.NewSource(1))
+	for round := 0; round < 100; round++ {
+		var idx prefixIndex
+		prefixes := make([]string, 20)
+		for i := range prefixes {
+			prefixes[i] = random(r)
+			idx.insert(prefixes[i])
+		}
+		keys := make([]string, 10)
+		for i := range keys {
+			keys[i] = random(r)
+		}
+		assert.Equal(t, naiveMatch(prefixes, keys), indexMatch(&idx, keys))
+	}
+}
+
+// BenchmarkWatchMatch matches hundreds of changed keys against hundreds of watcher prefixes.
+func BenchmarkWatchMatch(b *testing.B) {
+	var idx prefixIndex
+	prefixes := make([]string, 500)
+	for i := range prefixes {
+		prefixes[i] = fmt.Sprintf("service%d.client", i)
+		idx.insert(prefixes[i])
+	}
+	keys := make([]string, 500)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("service%d.client.timeout", i*2)
+	}
+
+	b.Run("naive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = naiveMatch(prefixes, keys)
+		}
+	})
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			_ = indexMatch(&idx, keys)
+		}
+	})
+}
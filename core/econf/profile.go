@@ -0,0 +1,102 @@
+package econf
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cast"
+)
+
+const (
+	// profilesKey is the top-level key holding all profiles, e.g. `profiles: {dev: {...}, prod: {...}}`.
+	profilesKey = "profiles"
+	// activeProfileKey is the top-level key selecting the profile merged over the base config.
+	activeProfileKey = "active_profile"
+)
+
+// ErrInvalidProfile ...
+var ErrInvalidProfile = errors.New("invalid profile, maybe not exist in profiles")
+
+// ActivateProfile merges the subtree `profiles.<name>` over the current config with default defaultConfiguration.
+func ActivateProfile(name string) error {
+	return defaultConfiguration.ActivateProfile(name)
+}
+
+// ActivateProfile merges the subtree `profiles.<name>` over the config without the active profile.
+// Values changed by the profile fire watchers like any other apply, then the OnChange callbacks run
+// as after a reload, since switching profiles is akin to loading other config.
+// The profile is remembered and merged again on every subsequent Load,
+// so a reload of the base config does not revert the profile values.
+// Switching profiles drops the values of the previous profile: keys only set by it are removed,
+// and keys it overrode get their base values back unless the new profile overrides them too.
+// Values set by Set are kept, unless the new profile overrides them.
+func (c *Configuration) ActivateProfile(name string) error {
+	c.mu.RLock()
+	profile, ok := lookupProfile(c.override, name)
+	c.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s, err: %w", name, ErrInvalidProfile)
+	}
+
+	// the profile is merged over the base as is, and only active once committed
+	if _, err := c.commit("", &profileLayer{name: name, values: profile}, func(base map[string]interface{}) (map[string]interface{}, []string) {
+		return base, nil
+	}); err != nil {
+		return err
+	}
+	c.fireOnChanges()
+	return nil
+}
+
+// ActiveProfile returns the name of the active profile, or empty if none.
+func (c *Configuration) ActiveProfile() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.activeProfile
+}
+
+// profileLayer is a profile merged over the base config by commit.
+type profileLayer struct {
+	name   string
+	values map[string]interface{}
+}
+
+// profileOf returns the active profile for conf with a copy of its values, or nil if none.
+// `active_profile` in conf wins over the profile activated at runtime, and the
+// profile subtree is looked up in conf first, then in the current config.
+// The profile only becomes active once conf is committed with it.
+func (c *Configuration) profileOf(conf map[string]interface{}) (*profileLayer, error) {
+	c.mu.RLock()
+	name := c.activeProfile
+	c.mu.RUnlock()
+	if v, ok := conf[activeProfileKey]; ok {
+		name = cast.ToString(v)
+	}
+	if name == "" {
+		return nil, nil
+	}
+
+	profile, ok := lookupProfile(conf, name)
+	if !ok {
+		c.mu.RLock()
+		profile, ok = lookupProfile(c.override, name)
+		c.mu.RUnlock()
+	}
+	if !ok {
+		return nil, fmt.Errorf("%s, err: %w", name, ErrInvalidProfile)
+	}
+	return &profileLayer{name: name, values: profile}, nil
+}
+
+// lookupProfile returns a deep copy of `profiles.<name>` in m.
+func lookupProfile(m map[string]interface{}, name string) (map[string]interface{}, bool) {
+	profiles, err := cast.ToStringMapE(m[profilesKey])
+	if err != nil {
+		return nil, false
+	}
+	profile, err := cast.ToStringMapE(profiles[name])
+	if err != nil {
+		return nil, false
+	}
+	return deepCopyMap(profile), true
+}
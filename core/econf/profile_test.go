@@ -0,0 +1,117 @@
+package econf
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+const profileConfig = `
+addr = "localhost"
+port = 80
+
+[profiles.dev]
+port = 8080
+
+[profiles.prod]
+addr = "example.com"
+port = 443
+`
+
+func TestActiveProfile(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`active_profile = "prod"`+profileConfig), toml.Unmarshal))
+	assert.Equal(t, "prod", v.ActiveProfile())
+	assert.Equal(t, "example.com", v.GetString("addr"))
+	assert.Equal(t, 443, v.GetInt("port"))
+
+	// reloading the base config keeps the profile values
+	assert.NoError(t, v.Load([]byte(profileConfig), toml.Unmarshal))
+	assert.Equal(t, 443, v.GetInt("port"))
+}
+
+func TestActivateProfile(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(profileConfig), toml.Unmarshal))
+	assert.Equal(t, "", v.ActiveProfile())
+	assert.Equal(t, 80, v.GetInt("port"))
+
+	changed := make(chan struct{}, 1)
+	v.Watch("port", func(*Configuration) {
+		changed <- struct{}{}
+	})
+
+	assert.NoError(t, v.ActivateProfile("dev"))
+	<-changed
+	assert.Equal(t, 8080, v.GetInt("port"))
+	assert.Equal(t, "localhost", v.GetString("addr"))
+	// the profile subtree itself is untouched
+	assert.Equal(t, 8080, v.GetInt("profiles.dev.port"))
+
+	err := v.ActivateProfile("not-exist")
+	assert.True(t, errors.Is(err, ErrInvalidProfile))
+	assert.Equal(t, "dev", v.ActiveProfile())
+}
+
+func TestSwitchProfile(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(profileConfig+`
+[profiles.dev.debug]
+pprof = true
+`), toml.Unmarshal))
+	fired := make(chan struct{}, 4)
+	v.OnChange(func(*Configuration) { fired <- struct{}{} })
+
+	assert.NoError(t, v.ActivateProfile("dev"))
+	<-fired
+	assert.Equal(t, 8080, v.GetInt("port"))
+	assert.True(t, v.GetBool("debug.pprof"))
+	assert.NoError(t, v.Set("name", "svc"))
+
+	assert.NoError(t, v.ActivateProfile("prod"))
+	<-fired
+	assert.Equal(t, "prod", v.ActiveProfile())
+	assert.Equal(t, 443, v.GetInt("port"))
+	assert.Equal(t, "example.com", v.GetString("addr"))
+	// the dev only key is gone, the base and the values set are kept
+	assert.False(t, v.IsSet("debug.pprof"))
+	assert.Nil(t, v.Get("debug"))
+	assert.Equal(t, "svc", v.GetString("name"))
+	assert.True(t, v.GetBool("profiles.dev.debug.pprof"))
+
+	// a reload merges the active profile over the reloaded base
+	assert.NoError(t, v.Load([]byte(`addr = "127.0.0.1"`), toml.Unmarshal))
+	assert.Equal(t, "example.com", v.GetString("addr"))
+	assert.NoError(t, v.ActivateProfile("dev"))
+	<-fired
+	assert.Equal(t, "127.0.0.1", v.GetString("addr"))
+	assert.Equal(t, 8080, v.GetInt("port"))
+}
+
+func TestActivateProfileRejected(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(profileConfig), toml.Unmarshal))
+	assert.NoError(t, v.ActivateProfile("dev"))
+	errRejected := errors.New("prod is not allowed here")
+	v.RegisterValidator(func(c *Configuration) error {
+		if c.GetString("addr") == "example.com" {
+			return errRejected
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, v.ActivateProfile("prod"), errRejected)
+	assert.Equal(t, "dev", v.ActiveProfile())
+	assert.Equal(t, 8080, v.GetInt("port"))
+	// later loads merge the profile still active, not the rejected one
+	assert.NoError(t, v.Load([]byte(`name = "demo"`+profileConfig), toml.Unmarshal))
+	assert.Equal(t, "dev", v.ActiveProfile())
+	assert.Equal(t, "demo", v.GetString("name"))
+	assert.Equal(t, 8080, v.GetInt("port"))
+
+	// a load selecting a rejected profile doesn't activate it either
+	assert.ErrorIs(t, v.Load([]byte(`active_profile = "prod"`+profileConfig), toml.Unmarshal), errRejected)
+	assert.Equal(t, "dev", v.ActiveProfile())
+}
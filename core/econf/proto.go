@@ -0,0 +1,72 @@
+package econf
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// ToProtoStruct converts the effective config with default defaultConfiguration.
+func ToProtoStruct() (*structpb.Struct, error) {
+	return defaultConfiguration.ToProtoStruct()
+}
+
+// ToProtoStruct converts the effective config into a google.protobuf.Struct, e.g. to distribute it over gRPC.
+// Per the structpb rules, all numbers become float64 values, and time values are formatted as RFC 3339 strings.
+func (c *Configuration) ToProtoStruct() (*structpb.Struct, error) {
+	c.mu.RLock()
+	m := normalizeValue(c.override)
+	c.mu.RUnlock()
+	s, err := structpb.NewStruct(m.(map[string]interface{}))
+	if err != nil {
+		return nil, fmt.Errorf("ToProtoStruct, err: %w", err)
+	}
+	return s, nil
+}
+
+// LoadFromProtoStruct merges a google.protobuf.Struct with default defaultConfiguration.
+func LoadFromProtoStruct(s *structpb.Struct) error {
+	return defaultConfiguration.LoadFromProtoStruct(s)
+}
+
+// LoadFromProtoStruct merges a google.protobuf.Struct into config like Load does.
+// Numbers are loaded as float64, and null values as nil.
+func (c *Configuration) LoadFromProtoStruct(s *structpb.Struct) error {
+	return c.apply(s.AsMap())
+}
+
+// normalizeValue converts v into plain maps with string keys, slices and scalars,
+// the types accepted by structpb.NewValue and encoding/json.
+func normalizeValue(v interface{}) interface{} {
+	switch vv := v.(type) {
+	case nil, bool, string, []byte,
+		int, int8, int16, int32, int64,
+		uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case time.Time:
+		return vv.Format(time.RFC3339Nano)
+	case time.Duration:
+		return vv.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprintf("%v", iter.Key().Interface())] = normalizeValue(iter.Value().Interface())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = normalizeValue(rv.Index(i).Interface())
+		}
+		return out
+	}
+	// left for structpb to report
+	return v
+}
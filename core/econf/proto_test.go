@@ -0,0 +1,54 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestProtoStruct(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte(`
+name = "app"
+port = 8080
+ratio = 0.5
+enabled = true
+hosts = ["a", "b"]
+
+[server.tls]
+enabled = false
+
+[[routes]]
+path = "/a"
+`), toml.Unmarshal))
+	assert.NoError(t, v.Set("labels", map[string]string{"env": "dev"}))
+	assert.NoError(t, v.Set("nothing", nil))
+
+	s, err := v.ToProtoStruct()
+	assert.NoError(t, err)
+	assert.Equal(t, "app", s.Fields["name"].GetStringValue())
+	assert.Equal(t, float64(8080), s.Fields["port"].GetNumberValue())
+	assert.True(t, s.Fields["enabled"].GetBoolValue())
+	assert.Len(t, s.Fields["hosts"].GetListValue().GetValues(), 2)
+	assert.IsType(t, &structpb.Value_NullValue{}, s.Fields["nothing"].GetKind())
+
+	loaded := New()
+	assert.NoError(t, loaded.LoadFromProtoStruct(s))
+	assert.Equal(t, "app", loaded.GetString("name"))
+	assert.Equal(t, 8080, loaded.GetInt("port"))
+	assert.Equal(t, 0.5, loaded.GetFloat64("ratio"))
+	assert.True(t, loaded.GetBool("enabled"))
+	assert.Equal(t, []string{"a", "b"}, loaded.GetStringSlice("hosts"))
+	assert.False(t, loaded.GetBool("server.tls.enabled"))
+	assert.True(t, loaded.IsSet("server.tls.enabled"))
+	assert.Equal(t, "/a", loaded.GetSliceStringMap("routes")[0]["path"])
+	assert.Equal(t, "dev", loaded.GetString("labels.env"))
+	assert.False(t, loaded.IsSet("nothing"))
+
+	// a second round trip is stable
+	again, err := loaded.ToProtoStruct()
+	assert.NoError(t, err)
+	assert.Equal(t, s.AsMap(), again.AsMap())
+}
@@ -0,0 +1,139 @@
+package econf
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cast"
+)
+
+var (
+	// ErrUnresolvedRef defines an error that a `${ref:key}` points to a key not exist in config.
+	ErrUnresolvedRef = errors.New("unresolved ref, maybe not exist in config")
+	// ErrCyclicRef defines an error that `${ref:key}` tokens reference each other in a cycle.
+	ErrCyclicRef = errors.New("cyclic ref")
+
+	refPattern = regexp.MustCompile(`\$\{ref:([^}]+)\}`)
+)
+
+// refResolver resolves `${ref:other.key}` tokens in string leaves of a parsed config.
+type refResolver struct {
+	flat     map[string]interface{}
+	resolved map[string]interface{}
+	visiting map[string]bool
+}
+
+// resolveRefs replaces `${ref:other.key}` tokens in the string leaves of conf by the value of
+// the referenced flattened key in conf. A leaf that is exactly one token takes the referenced
+// value as is, otherwise the referenced values are formatted into the string.
+func resolveRefs(conf map[string]interface{}, sep string) error {
+	r := &refResolver{
+		flat:     make(map[string]interface{}),
+		resolved: make(map[string]interface{}),
+		visiting: make(map[string]bool),
+	}
+	lookup("", conf, r.flat, sep, maxDepth())
+	_, err := r.walk("", conf, sep, true)
+	return err
+}
+
+// walk returns v, the value of key, with its refs resolved, resolving maps and lists in place.
+// If flat, string leaves are resolved by their flattened key, so they are resolved once even when
+// referenced. Lists aren't flattened, so their elements, and the maps in them, are resolved by value,
+// key only naming them in errors, e.g. `servers.1.addr`.
+func (r *refResolver) walk(key string, v interface{}, sep string, flat bool) (interface{}, error) {
+	switch vv := v.(type) {
+	case map[string]interface{}:
+		for k, e := range vv {
+			resolved, err := r.walk(joinKey(key, k, sep), e, sep, flat)
+			if err != nil {
+				return nil, err
+			}
+			vv[k] = resolved
+		}
+		return vv, nil
+	case map[interface{}]interface{}:
+		return r.walk(key, cast.ToStringMap(vv), sep, flat)
+	case []interface{}:
+		for i, e := range vv {
+			resolved, err := r.walk(joinKey(key, strconv.Itoa(i), sep), e, sep, false)
+			if err != nil {
+				return nil, err
+			}
+			vv[i] = resolved
+		}
+		return vv, nil
+	case []map[string]interface{}:
+		// as decoded by toml for arrays of tables
+		for i, e := range vv {
+			if _, err := r.walk(joinKey(key, strconv.Itoa(i), sep), e, sep, false); err != nil {
+				return nil, err
+			}
+		}
+		return vv, nil
+	case string:
+		if _, ok := r.flat[key]; flat && ok {
+			return r.resolveKey(key)
+		}
+		resolved, err := r.resolveString(vv)
+		if err != nil {
+			return nil, fmt.Errorf("%s, err: %w", key, err)
+		}
+		return resolved, nil
+	}
+	return v, nil
+}
+
+func (r *refResolver) resolveKey(key string) (interface{}, error) {
+	if v, ok := r.resolved[key]; ok {
+		return v, nil
+	}
+	if r.visiting[key] {
+		return nil, fmt.Errorf("%s, err: %w", key, ErrCyclicRef)
+	}
+	v, ok := r.flat[key]
+	if !ok {
+		return nil, fmt.Errorf("%s, err: %w", key, ErrUnresolvedRef)
+	}
+	str, ok := v.(string)
+	if !ok {
+		return v, nil
+	}
+
+	r.visiting[key] = true
+	resolved, err := r.resolveString(str)
+	delete(r.visiting, key)
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[key] = resolved
+	return resolved, nil
+}
+
+func (r *refResolver) resolveString(str string) (interface{}, error) {
+	matches := refPattern.FindAllStringSubmatchIndex(str, -1)
+	if len(matches) == 0 {
+		return str, nil
+	}
+	// a value that is exactly one ref keeps the type of the referenced value
+	if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(str) {
+		return r.resolveKey(str[matches[0][2]:matches[0][3]])
+	}
+
+	var sb strings.Builder
+	last := 0
+	for _, m := range matches {
+		v, err := r.resolveKey(str[m[2]:m[3]])
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(str[last:m[0]])
+		sb.WriteString(cast.ToString(v))
+		last = m[1]
+	}
+	sb.WriteString(str[last:])
+	return sb.String(), nil
+}
@@ -0,0 +1,93 @@
+package econf
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefs(t *testing.T) {
+	content := []byte(`
+host = "example.com"
+port = 8080
+url = "http://${ref:server.addr}/api"
+hosts = ["${ref:host}", "b"]
+
+[server]
+addr = "${ref:host}:${ref:server.port}"
+port = "${ref:port}"
+`)
+
+	t.Run("disabled", func(t *testing.T) {
+		v := New()
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Equal(t, "${ref:port}", v.GetString("server.port"))
+	})
+
+	t.Run("chain", func(t *testing.T) {
+		withOptions(t, WithRefs(true))
+		v := New()
+		assert.NoError(t, v.Load(content, toml.Unmarshal))
+		assert.Equal(t, int64(8080), v.Get("server.port"))
+		assert.Equal(t, "example.com:8080", v.GetString("server.addr"))
+		assert.Equal(t, "http://example.com:8080/api", v.GetString("url"))
+		assert.Equal(t, []string{"example.com", "b"}, v.GetStringSlice("hosts"))
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		withOptions(t, WithRefs(true))
+		v := New()
+		err := v.Load([]byte(`
+a = "${ref:b}"
+b = "x${ref:c}"
+c = "${ref:a}"
+`), toml.Unmarshal)
+		assert.True(t, errors.Is(err, ErrCyclicRef))
+	})
+
+	t.Run("unresolved", func(t *testing.T) {
+		withOptions(t, WithRefs(true))
+		v := New()
+		err := v.Load([]byte(`a = "${ref:not.exist}"`), toml.Unmarshal)
+		assert.True(t, errors.Is(err, ErrUnresolvedRef))
+	})
+
+	t.Run("list of maps", func(t *testing.T) {
+		withOptions(t, WithRefs(true))
+		v := New()
+		assert.NoError(t, v.Load([]byte(`
+host = "example.com"
+groups = [["${ref:host}"]]
+
+[[servers]]
+addr = "${ref:host}:80"
+
+[[servers]]
+addr = "${ref:host}"
+`), toml.Unmarshal))
+		assert.Equal(t, []map[string]interface{}{
+			{"addr": "example.com:80"},
+			{"addr": "example.com"},
+		}, v.Get("servers"))
+		assert.Equal(t, []interface{}{[]interface{}{"example.com"}}, v.Get("groups"))
+		// JSON decodes lists of objects as []interface{}
+		v = New()
+		assert.NoError(t, v.Load([]byte(`{"host": "example.com", "servers": [{"addr": "${ref:host}"}]}`), json.Unmarshal))
+		assert.Equal(t, []interface{}{map[string]interface{}{"addr": "example.com"}}, v.Get("servers"))
+
+		err := v.Load([]byte(`
+host = "example.com"
+
+[[servers]]
+addr = "${ref:host}"
+
+[[servers]]
+addr = "${ref:missing}"
+`), toml.Unmarshal)
+		assert.ErrorIs(t, err, ErrUnresolvedRef)
+		assert.ErrorContains(t, err, "servers.1.addr")
+	})
+}
@@ -0,0 +1,43 @@
+package econf
+
+import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+)
+
+// WatchSignal reloads defaultConfiguration on receipt of sig.
+func WatchSignal(sig ...os.Signal) (stop func()) {
+	return defaultConfiguration.WatchSignal(sig...)
+}
+
+// WatchSignal calls ReloadNow on receipt of any of sig, SIGHUP if none given,
+// until the returned stop function is called; calling it again does nothing. Every call installs
+// its own handler, so several Configurations may watch the same signal. Reload errors are ignored
+// and the previous config is kept.
+func (c *Configuration) WatchSignal(sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				_ = c.ReloadNow()
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(ch)
+			close(done)
+		})
+	}
+}
@@ -0,0 +1,49 @@
+//go:build !windows
+// +build !windows
+
+package econf
+
+import (
+	"syscall"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatchSignal(t *testing.T) {
+	withOptions(t)
+	load := func(content string) (*Configuration, *fakeDataSource, chan string) {
+		v := New()
+		fired := make(chan string, 4)
+		v.OnChange(func(c *Configuration) {
+			fired <- c.GetString("foo")
+		})
+		ds := newFakeDataSource(content)
+		assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+		<-fired
+		return v, ds, fired
+	}
+	a, dsA, firedA := load(`foo = "a"`)
+	defer dsA.Close()
+	b, dsB, firedB := load(`foo = "b"`)
+	defer dsB.Close()
+
+	stopA := a.WatchSignal(syscall.SIGUSR1)
+	stopB := b.WatchSignal(syscall.SIGUSR1)
+	defer stopB()
+
+	dsA.set(`foo = "a2"`)
+	dsB.set(`foo = "b2"`)
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Equal(t, "a2", <-firedA)
+	assert.Equal(t, "b2", <-firedB)
+
+	stopA()
+	// stopping twice is a no-op
+	assert.NotPanics(t, stopA)
+	dsB.set(`foo = "b3"`)
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	assert.Equal(t, "b3", <-firedB)
+	assert.Equal(t, "a2", a.GetString("foo"))
+}
@@ -0,0 +1,13 @@
+package econf
+
+// snapshot is the state read by find, replaced as a whole by each update so readers never wait for the lock.
+// Its maps are never mutated once published, as update works on a copy.
+type snapshot struct {
+	override map[string]interface{}
+	fallback func(key string) (interface{}, bool)
+}
+
+// publish replaces the snapshot by the current state, with lock held.
+func (c *Configuration) publish() {
+	c.snapshot.Store(&snapshot{override: c.override, fallback: c.fallback})
+}
@@ -0,0 +1,111 @@
+package econf
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/gotomicro/ego/core/util/xmap"
+)
+
+func TestSnapshotConsistentReads(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server", map[string]interface{}{"port": 0, "host": 0}))
+
+	const updates = 200
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for r := 0; r < 4; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			last := 0
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				// both leaves are always read from the same update
+				m := v.GetStringMap("server")
+				assert.Equal(t, m["port"], m["host"])
+				port := v.GetInt("server.port")
+				assert.GreaterOrEqual(t, port, last)
+				last = port
+			}
+		}()
+	}
+	for i := 1; i <= updates; i++ {
+		assert.NoError(t, v.Set("server", map[string]interface{}{"port": i, "host": i}))
+	}
+	close(done)
+	wg.Wait()
+	assert.Equal(t, updates, v.GetInt("server.port"))
+	assert.Equal(t, map[string]interface{}{"port": updates, "host": updates}, v.GetStringMap("server"))
+}
+
+// BenchmarkReadDuringReload compares reads of the config tree while it is reloaded,
+// holding the read lock as find used to, and from the snapshot.
+func BenchmarkReadDuringReload(b *testing.B) {
+	withOptions(b, WithDisableCache(true))
+
+	tree := make(map[string]interface{})
+	for i := 0; i < 5000; i++ {
+		tree[fmt.Sprintf("k%d", i)] = map[string]interface{}{"value": i}
+	}
+	v := New()
+	_ = v.Set("tree", tree)
+	_ = v.Set("server.port", 80)
+
+	reload := func(b *testing.B, read func()) {
+		done := make(chan struct{})
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					_ = v.Set("tree", tree)
+				}
+			}
+		}()
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				read()
+			}
+		})
+		b.StopTimer()
+		close(done)
+		wg.Wait()
+	}
+
+	paths := []string{"server", "port"}
+	b.Run("locked", func(b *testing.B) {
+		reload(b, func() {
+			v.mu.RLock()
+			_ = xmap.DeepSearchInMap(v.override, paths[:1]...)[paths[1]]
+			v.mu.RUnlock()
+		})
+	})
+	b.Run("snapshot", func(b *testing.B) {
+		reload(b, func() {
+			_ = v.findInTree("server.port")
+		})
+	})
+}
+
+func TestFindDoesNotModifyTree(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("mysql.primary.dsn", "a"))
+	assert.Nil(t, v.Get("mysql.replica.dsn"))
+	assert.Nil(t, v.Get("mysql.primary.dsn.user"))
+	assert.Equal(t, map[string]interface{}{
+		"mysql": map[string]interface{}{"primary": map[string]interface{}{"dsn": "a"}},
+	}, v.override)
+}
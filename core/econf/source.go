@@ -0,0 +1,74 @@
+package econf
+
+import (
+	"reflect"
+)
+
+// sourceSet is the source name of the values set by Set.
+const sourceSet = "set"
+
+// SourceOf returns the name of the source which set the value of key with default defaultConfiguration.
+func SourceOf(key string) string {
+	return defaultConfiguration.SourceOf(key)
+}
+
+// SourceOf returns the name of the source which set the effective value of the leaf key:
+// the WithSourceName of the LoadFromDataSource which loaded it, or "set" if it was set by Set.
+// It returns an empty string for keys that are not leaves or were set by an unnamed source, e.g. Load.
+func (c *Configuration) SourceOf(key string) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.sources[key]
+}
+
+// leavesOf returns the leaf keys of val set at key.
+func (c *Configuration) leavesOf(key string, val interface{}) []string {
+	if m, ok := val.(map[string]interface{}); ok {
+		data := make(map[string]interface{})
+		lookup(key, m, data, c.keyDelim, maxDepth())
+		return mapKeys(data)
+	}
+	return []string{key}
+}
+
+// wonLeaves returns the leaf keys of layer whose value is effective in the merged override.
+func (c *Configuration) wonLeaves(override, layer map[string]interface{}) []string {
+	layerLeaves := make(map[string]interface{})
+	lookup("", layer, layerLeaves, c.keyDelim, maxDepth())
+	leaves := make(map[string]interface{})
+	lookup("", override, leaves, c.keyDelim, maxDepth())
+	won := make([]string, 0, len(layerLeaves))
+	for k, v := range layerLeaves {
+		if merged, ok := leaves[k]; ok && reflect.DeepEqual(merged, v) {
+			won = append(won, k)
+		}
+	}
+	return won
+}
+
+// recordSources records set as set by source and drops the sources of keys no longer in leaves, with lock held.
+// A nil leaves drops none, as after refreshInitial.
+func (c *Configuration) recordSources(source string, set []string, leaves map[string]interface{}) {
+	if c.sources == nil {
+		c.sources = make(map[string]string)
+	}
+	for _, k := range set {
+		c.sources[k] = source
+	}
+	if leaves == nil {
+		return
+	}
+	for k := range c.sources {
+		if _, ok := leaves[k]; !ok {
+			delete(c.sources, k)
+		}
+	}
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,57 @@
+package econf
+
+import (
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSourceOf(t *testing.T) {
+	withOptions(t)
+	v := New()
+	base := newFakeDataSource(`
+[server]
+host = "localhost"
+port = 80
+`)
+	defer base.Close()
+	env := newFakeDataSource(`
+[server]
+port = 8080
+`)
+	defer env.Close()
+	assert.NoError(t, v.LoadFromDataSource(base, toml.Unmarshal, WithSourceName("base")))
+	assert.NoError(t, v.LoadFromDataSource(env, toml.Unmarshal, WithSourceName("env")))
+	assert.Equal(t, "", defaultContainer.SourceName)
+
+	assert.Equal(t, int64(8080), v.Get("server.port"))
+	assert.Equal(t, "env", v.SourceOf("server.port"))
+	assert.Equal(t, "base", v.SourceOf("server.host"))
+	assert.Equal(t, "", v.SourceOf("server"))
+	assert.Equal(t, "", v.SourceOf("missing"))
+
+	assert.NoError(t, v.Set("server.host", "example.com"))
+	assert.Equal(t, "set", v.SourceOf("server.host"))
+	assert.Equal(t, "set", v.Clone().SourceOf("server.host"))
+
+	// a reload keeps the name of its data source
+	env.set(`
+[server]
+port = 9090
+timeout = "1s"
+`)
+	assert.NoError(t, v.ReloadNow())
+	assert.Equal(t, "env", v.SourceOf("server.port"))
+	assert.Equal(t, "env", v.SourceOf("server.timeout"))
+
+	// an unnamed layer and replaced keys have no source
+	assert.NoError(t, v.Load([]byte(`
+[server]
+port = 1
+`), toml.Unmarshal))
+	assert.Equal(t, "", v.SourceOf("server.port"))
+	assert.NoError(t, v.Set("server", "flat"))
+	assert.Equal(t, "set", v.SourceOf("server"))
+	assert.Equal(t, "", v.SourceOf("server.timeout"))
+}
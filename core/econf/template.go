@@ -0,0 +1,312 @@
+package econf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cast"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// templateDefaultTag is the struct tag of the value of a field in templates.
+	templateDefaultTag = "default"
+	// templateCommentTag is the struct tag of the comment of a field in templates.
+	templateCommentTag = "comment"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// templateField is a field of a templateMap.
+type templateField struct {
+	name    string
+	comment string
+	// value is a templateMap for a struct, a []interface{} for a slice, or a scalar
+	value interface{}
+}
+
+// templateMap is a struct of a template, keeping the order of its fields.
+type templateMap []templateField
+
+// MarshalJSON encodes the fields in order.
+func (m templateMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range m {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, err := json.Marshal(field.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// GenerateTemplate returns a config template for the struct v, or a pointer to it, in format `yaml` or `json`,
+// e.g. to author the config decoded into v by UnmarshalKey. Fields are named by the tag of WithTagName
+// and keep their order. A field's value is its `default` tag, parsed as the type of the field, or else
+// its value in v. A field's `comment` tag precedes it in YAML, JSON having no comments.
+// Fields with a tag `-`, or `,remain`, are skipped, and embedded structs are squashed like UnmarshalKey does.
+// A struct type nested in itself, such as a linked list node, is null below its outermost occurrence.
+func GenerateTemplate(v interface{}, format string) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			rv = reflect.New(rv.Type().Elem())
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("GenerateTemplate, err: unsupported target %T", v)
+	}
+	m, err := templateStruct("", rv, make(map[reflect.Type]bool))
+	if err != nil {
+		return nil, err
+	}
+	switch ConfigType(format) {
+	case ConfigTypeYaml:
+		return encodePooled(func(buf *bytes.Buffer) error {
+			enc := yaml.NewEncoder(buf)
+			enc.SetIndent(2)
+			node, err := templateNode(m)
+			if err != nil {
+				return err
+			}
+			if err := enc.Encode(node); err != nil {
+				return err
+			}
+			return enc.Close()
+		})
+	case ConfigTypeJSON:
+		return json.MarshalIndent(m, "", "  ")
+	}
+	return nil, fmt.Errorf("GenerateTemplate, err: unsupported format %q", format)
+}
+
+// templateStruct returns the fields of the struct rv, whose key is key, as a templateMap.
+// path holds the struct types being generated, so a type nested in itself, e.g. by a `Next *Node` field, ends
+// the recursion as nil.
+func templateStruct(key string, rv reflect.Value, path map[reflect.Type]bool) (templateMap, error) {
+	var m templateMap
+	rt := rv.Type()
+	path[rt] = true
+	defer delete(path, rt)
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(sf.Tag.Get(defaultContainer.TagName), ",")
+		if name == "-" || hasTagOption(opts, "remain") {
+			continue
+		}
+		fv := rv.Field(i)
+		if sf.Anonymous && name == "" && (defaultContainer.Squash || hasTagOption(opts, "squash")) {
+			for fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv = reflect.New(fv.Type().Elem())
+				}
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct && !path[fv.Type()] {
+				embedded, err := templateStruct(key, fv, path)
+				if err != nil {
+					return nil, err
+				}
+				m = append(m, embedded...)
+				continue
+			}
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		fieldKey := joinKey(key, name, defaultKeyDelim)
+		value, err := templateValue(fieldKey, fv, sf.Tag, path)
+		if err != nil {
+			return nil, err
+		}
+		m = append(m, templateField{name: name, comment: sf.Tag.Get(templateCommentTag), value: value})
+	}
+	return m, nil
+}
+
+// templateValue returns the template value of the field rv, whose key is key, tagged with tag.
+func templateValue(key string, rv reflect.Value, tag reflect.StructTag, path map[reflect.Type]bool) (interface{}, error) {
+	if def, ok := tag.Lookup(templateDefaultTag); ok {
+		value, err := parseTemplateDefault(rv.Type(), def)
+		if err != nil {
+			return nil, fmt.Errorf("%s default %q, err: %w", key, def, err)
+		}
+		return value, nil
+	}
+	return templateOf(key, rv, path)
+}
+
+// templateOf returns the template value of rv, whose key is key.
+func templateOf(key string, rv reflect.Value, path map[reflect.Type]bool) (interface{}, error) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			if rv.Kind() == reflect.Ptr && rv.Type().Elem().Kind() == reflect.Struct && !path[rv.Type().Elem()] {
+				return templateStruct(key, reflect.New(rv.Type().Elem()).Elem(), path)
+			}
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	switch {
+	case rv.Type() == durationType:
+		return time.Duration(rv.Int()).String(), nil
+	case rv.Type() == timeType:
+		return rv.Interface().(time.Time).Format(time.RFC3339), nil
+	}
+	switch rv.Kind() {
+	case reflect.Struct:
+		if path[rv.Type()] {
+			return nil, nil
+		}
+		return templateStruct(key, rv, path)
+	case reflect.Slice, reflect.Array:
+		out := make([]interface{}, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			elem, err := templateOf(joinKey(key, cast.ToString(i), defaultKeyDelim), rv.Index(i), path)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, elem)
+		}
+		return out, nil
+	case reflect.Map:
+		// json and yaml encode maps with sorted keys
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			k := cast.ToString(iter.Key().Interface())
+			elem, err := templateOf(joinKey(key, k, defaultKeyDelim), iter.Value(), path)
+			if err != nil {
+				return nil, err
+			}
+			out[k] = elem
+		}
+		return out, nil
+	}
+	return rv.Interface(), nil
+}
+
+// parseTemplateDefault parses the default tag def as a value of type t.
+// Slices are split on commas, durations and times are kept as strings once they are checked.
+func parseTemplateDefault(t reflect.Type, def string) (interface{}, error) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case durationType:
+		if _, err := time.ParseDuration(def); err != nil {
+			return nil, err
+		}
+		return def, nil
+	case timeType:
+		if _, err := cast.ToTimeE(def); err != nil {
+			return nil, err
+		}
+		return def, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return cast.ToBoolE(def)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cast.ToInt64E(def)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return cast.ToUint64E(def)
+	case reflect.Float32, reflect.Float64:
+		return cast.ToFloat64E(def)
+	case reflect.String:
+		return def, nil
+	case reflect.Slice, reflect.Array:
+		parts := splitCSV(def)
+		out := make([]interface{}, 0, len(parts))
+		for _, part := range parts {
+			elem, err := parseTemplateDefault(t.Elem(), part)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, elem)
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// templateNode returns value as a YAML node, with the comments of the fields of templateMaps.
+func templateNode(value interface{}) (*yaml.Node, error) {
+	switch v := value.(type) {
+	case templateMap:
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, field := range v {
+			key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: field.name, HeadComment: field.comment}
+			elem, err := templateNode(field.value)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, key, elem)
+		}
+		return node, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		node := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+		for _, k := range keys {
+			elem, err := templateNode(v[k])
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: k}, elem)
+		}
+		return node, nil
+	case []interface{}:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq"}
+		for _, e := range v {
+			elem, err := templateNode(e)
+			if err != nil {
+				return nil, err
+			}
+			node.Content = append(node.Content, elem)
+		}
+		return node, nil
+	}
+	node := &yaml.Node{}
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+	return node, nil
+}
+
+// hasTagOption reports if the comma separated options of a struct tag contain opt.
+func hasTagOption(opts string, opt string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
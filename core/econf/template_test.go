@@ -0,0 +1,94 @@
+package econf
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+type templateTLS struct {
+	Enable bool   `mapstructure:"enable" default:"true" comment:"enables TLS"`
+	Cert   string `mapstructure:"cert"`
+}
+
+type TemplateBase struct {
+	Name string `mapstructure:"name" default:"api"`
+}
+
+type templateServer struct {
+	TemplateBase `mapstructure:",squash"`
+	Port         int                    `mapstructure:"port" default:"8080" comment:"listen port"`
+	Timeout      time.Duration          `mapstructure:"timeout" default:"3s"`
+	Hosts        []string               `mapstructure:"hosts" default:"a, b"`
+	Ratio        float64                `mapstructure:"ratio"`
+	TLS          *templateTLS           `mapstructure:"tls"`
+	Extra        map[string]interface{} `mapstructure:",remain"`
+	Ignored      string                 `mapstructure:"-"`
+}
+
+func TestGenerateTemplate(t *testing.T) {
+	out, err := GenerateTemplate(&templateServer{Ratio: 0.5, TLS: &templateTLS{Cert: "/etc/cert.pem"}}, "yaml")
+	assert.NoError(t, err)
+	expected := `name: api
+# listen port
+port: 8080
+timeout: 3s
+hosts:
+  - a
+  - b
+ratio: 0.5
+tls:
+  # enables TLS
+  enable: true
+  cert: /etc/cert.pem
+`
+	assert.Equal(t, expected, string(out))
+
+	// the template decodes into the defaults
+	v := New()
+	assert.NoError(t, v.Load(out, yaml.Unmarshal))
+	var decoded templateServer
+	assert.NoError(t, v.UnmarshalKey("", &decoded))
+	assert.Equal(t, templateServer{
+		TemplateBase: TemplateBase{Name: "api"},
+		Port:         8080,
+		Timeout:      3 * time.Second,
+		Hosts:        []string{"a", "b"},
+		Ratio:        0.5,
+		TLS:          &templateTLS{Enable: true, Cert: "/etc/cert.pem"},
+	}, decoded)
+
+	out, err = GenerateTemplate(templateServer{}, "json")
+	assert.NoError(t, err)
+	assert.True(t, json.Valid(out))
+	assert.Contains(t, string(out), `"port": 8080`)
+	assert.Contains(t, string(out), `"tls": {
+    "enable": true,
+    "cert": ""
+  }`)
+
+	_, err = GenerateTemplate(templateServer{}, "ini")
+	assert.Error(t, err)
+	_, err = GenerateTemplate(3, "yaml")
+	assert.Error(t, err)
+	type invalid struct {
+		Port int `mapstructure:"port" default:"http"`
+	}
+	_, err = GenerateTemplate(invalid{}, "yaml")
+	assert.ErrorContains(t, err, "port default")
+}
+
+type templateList struct {
+	Name     string         `mapstructure:"name" default:"root"`
+	Next     *templateList  `mapstructure:"next"`
+	Children []templateList `mapstructure:"children"`
+}
+
+func TestGenerateTemplateCycle(t *testing.T) {
+	out, err := GenerateTemplate(templateList{Next: &templateList{}}, "yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "name: root\nnext: null\nchildren: []\n", string(out))
+}
@@ -0,0 +1,70 @@
+package econf
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+// ReadStats returns the number of reads of each key of defaultConfiguration, if WithReadTracing is enabled.
+func ReadStats() map[string]int64 {
+	return defaultConfiguration.ReadStats()
+}
+
+// ReadStats returns the number of reads of each key since WithReadTracing was enabled,
+// e.g. to find hot or unused keys. Keys never read are missing.
+func (c *Configuration) ReadStats() map[string]int64 {
+	stats := make(map[string]int64)
+	c.readStats.Range(func(k, v interface{}) bool {
+		stats[k.(string)] = v.(*atomic.Int64).Load()
+		return true
+	})
+	return stats
+}
+
+// UnusedKeys returns the sorted leaf keys of defaultConfiguration never read, if WithReadTracing is enabled.
+func UnusedKeys() []string {
+	return defaultConfiguration.UnusedKeys()
+}
+
+// UnusedKeys returns the sorted leaf keys never read since WithReadTracing was enabled, e.g. to clean up
+// stale config in a shutdown hook. A leaf is used if it, or a subtree holding it, was read.
+// All keys are unused if WithReadTracing is disabled.
+func (c *Configuration) UnusedKeys() []string {
+	c.mu.RLock()
+	leaves := c.traverse(c.keyDelim)
+	c.mu.RUnlock()
+
+	unused := make([]string, 0)
+	for key := range leaves {
+		if !c.isRead(key) {
+			unused = append(unused, key)
+		}
+	}
+	sort.Strings(unused)
+	return unused
+}
+
+// isRead reports if key, or one of its ancestors, was read.
+func (c *Configuration) isRead(key string) bool {
+	var prefix string
+	for _, path := range splitKey(key, c.keyDelim) {
+		prefix = joinKey(prefix, path, c.keyDelim)
+		if _, ok := c.readStats.Load(prefix); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// traceRead counts a read of key, with aliases resolved, if EnableReadTracing is on.
+func (c *Configuration) traceRead(key string) {
+	if !defaultContainer.EnableReadTracing {
+		return
+	}
+	key = c.canonicalKey(key)
+	counter, ok := c.readStats.Load(key)
+	if !ok {
+		counter, _ = c.readStats.LoadOrStore(key, &atomic.Int64{})
+	}
+	counter.(*atomic.Int64).Add(1)
+}
@@ -0,0 +1,65 @@
+package econf
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadStats(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("feature.enabled", true))
+	assert.NoError(t, v.Set("feature.limit", 10))
+	_ = v.Get("feature.enabled")
+	assert.Empty(t, v.ReadStats())
+
+	withOptions(t, WithReadTracing(true))
+	_ = v.Get("feature.enabled")
+	_ = v.GetBool("feature.enabled")
+	_ = v.GetInt("feature.limit")
+	_ = v.Get("missing")
+	assert.Equal(t, map[string]int64{
+		"feature.enabled": 2,
+		"feature.limit":   1,
+		"missing":         1,
+	}, v.ReadStats())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = v.GetInt("feature.limit")
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int64(11), v.ReadStats()["feature.limit"])
+
+	// hits of the typed cache are counted too
+	withOptions(t, WithTypedCache(true))
+	_ = v.GetInt("feature.limit")
+	_ = v.GetInt("feature.limit")
+	assert.Equal(t, int64(13), v.ReadStats()["feature.limit"])
+}
+
+func TestUnusedKeys(t *testing.T) {
+	withOptions(t, WithReadTracing(true))
+	v := New()
+	assert.NoError(t, v.Set("server.host", "localhost"))
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("db.dsn", "dsn"))
+	assert.NoError(t, v.Set("db.pool.max", 10))
+	assert.NoError(t, v.Set("legacy.timeout", "1s"))
+	assert.NoError(t, v.Set("legacy.retries", 3))
+	assert.NoError(t, v.RegisterAlias("db.url", "db.dsn"))
+	assert.Equal(t, []string{"db.dsn", "db.pool.max", "legacy.retries", "legacy.timeout", "server.host", "server.port"}, v.UnusedKeys())
+
+	_ = v.GetString("server.host")
+	_ = v.GetStringMap("db.pool")
+	_ = v.GetString("db.url")
+	assert.Equal(t, []string{"legacy.retries", "legacy.timeout", "server.port"}, v.UnusedKeys())
+
+	_ = v.GetStringMap("server")
+	assert.Equal(t, []string{"legacy.retries", "legacy.timeout"}, v.UnusedKeys())
+}
@@ -0,0 +1,35 @@
+package econf
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RegisterValidator registers a validator with default defaultConfiguration.
+func RegisterValidator(fn func(*Configuration) error) {
+	defaultConfiguration.RegisterValidator(fn)
+}
+
+// RegisterValidator registers a validator gating every change made by Load, Set and Apply.
+// The validator receives the candidate config, which is committed only if all validators pass.
+// Validators run without holding the lock, but must not modify config themselves.
+func (c *Configuration) RegisterValidator(fn func(*Configuration) error) {
+	c.mu.Lock()
+	c.validators = append(c.validators[:len(c.validators):len(c.validators)], fn)
+	c.mu.Unlock()
+}
+
+// validate runs the validators against a candidate override.
+func (c *Configuration) validate(override map[string]interface{}, validators []func(*Configuration) error) error {
+	candidate := &Configuration{
+		override: override,
+		keyDelim: c.keyDelim,
+		keyMap:   &sync.Map{},
+	}
+	for _, validator := range validators {
+		if err := validator(candidate); err != nil {
+			return fmt.Errorf("validate config, err: %w", err)
+		}
+	}
+	return nil
+}
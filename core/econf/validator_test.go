@@ -0,0 +1,80 @@
+package econf
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/assert"
+)
+
+var errInvalidPort = errors.New("invalid port")
+
+func TestRegisterValidator(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Load([]byte("[server]\nport = 8080"), toml.Unmarshal))
+	v.RegisterValidator(func(candidate *Configuration) error {
+		if port := candidate.GetInt("server.port"); port <= 0 || port > 65535 {
+			return errInvalidPort
+		}
+		return nil
+	})
+
+	t.Run("set rejected", func(t *testing.T) {
+		err := v.Set("server.port", int64(70000))
+		assert.True(t, errors.Is(err, errInvalidPort))
+		assert.Equal(t, 8080, v.GetInt("server.port"))
+		assert.Equal(t, int64(8080), v.GetStringMap("server")["port"])
+	})
+
+	t.Run("set accepted", func(t *testing.T) {
+		assert.NoError(t, v.Set("server.port", int64(9090)))
+		assert.Equal(t, 9090, v.GetInt("server.port"))
+	})
+
+	t.Run("load rejected", func(t *testing.T) {
+		err := v.Load([]byte("[server]\nport = 0\nhost = \"example.com\""), toml.Unmarshal)
+		assert.True(t, errors.Is(err, errInvalidPort))
+		assert.Equal(t, 9090, v.GetInt("server.port"))
+		assert.Nil(t, v.Get("server.host"))
+	})
+}
+
+func TestSetNested(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("a.b.c", 1))
+	assert.NoError(t, v.Set("a.b.d", "2"))
+	assert.Equal(t, map[string]interface{}{"c": 1, "d": "2"}, v.GetStringMap("a.b"))
+	// Set must not leak the leaf to the root
+	assert.Nil(t, v.Get("c"))
+}
+
+func TestReentrantSet(t *testing.T) {
+	withOptions(t)
+	v := New()
+	v.RegisterValidator(func(candidate *Configuration) error {
+		// reading the original config while validating must not deadlock
+		_ = v.GetString("foo")
+		return nil
+	})
+	done := make(chan string, 2)
+	v.OnChange(func(c *Configuration) {
+		// Set from a callback must not deadlock
+		assert.NoError(t, c.Set("seen", c.GetString("foo")))
+		done <- c.GetString("seen")
+	})
+
+	ds := newFakeDataSource(`foo = "bar"`)
+	defer ds.Close()
+	assert.NoError(t, v.LoadFromDataSource(ds, toml.Unmarshal, WithSyncOnChange(true)))
+	assert.Equal(t, "bar", <-done)
+
+	ds.update(`foo = "baz"`)
+	select {
+	case seen := <-done:
+		assert.Equal(t, "baz", seen)
+	case <-time.After(time.Second):
+		t.Fatal("reentrant Set deadlocked")
+	}
+}
@@ -0,0 +1,209 @@
+package econf
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// logLevelKey is the key followed by WatchLogLevel.
+const logLevelKey = "log.level"
+
+// Watch registers a callback with default defaultConfiguration.
+func Watch(prefix string, fn func(*Configuration)) {
+	defaultConfiguration.Watch(prefix, fn)
+}
+
+// Watch registers a callback fired when a key starting with prefix changes.
+// Callbacks run one at a time in order on a goroutine of their own, so a callback must not block
+// waiting for another one.
+func (c *Configuration) Watch(prefix string, fn func(*Configuration)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.watchers == nil {
+		c.watchers = make(map[string][]func(*Configuration))
+	}
+	c.watchers[prefix] = append(c.watchers[prefix], fn)
+	c.watchPrefixes.insert(prefix)
+}
+
+// WatchKey registers a callback with default defaultConfiguration.
+func WatchKey(key string, fn func(*Configuration)) {
+	defaultConfiguration.WatchKey(key, fn)
+}
+
+// WatchKey registers a callback fired when exactly the key changes,
+// unlike Watch which also matches `log.levels` for `log.level`.
+// It runs like the callbacks of Watch.
+func (c *Configuration) WatchKey(key string, fn func(*Configuration)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keyWatchers == nil {
+		c.keyWatchers = make(map[string][]func(*Configuration))
+	}
+	c.keyWatchers[key] = append(c.keyWatchers[key], fn)
+}
+
+// WatcherPrefixes returns the prefixes of the registered watchers with default defaultConfiguration.
+func WatcherPrefixes() []string {
+	return defaultConfiguration.WatcherPrefixes()
+}
+
+// WatcherPrefixes returns the sorted prefixes and keys registered by Watch, WatchKey and WatchChan,
+// once per registered watcher, so a prefix registered twice appears twice, e.g. to detect leaked watchers.
+func (c *Configuration) WatcherPrefixes() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	prefixes := make([]string, 0, len(c.watchers)+len(c.keyWatchers)+len(c.chanWatchers))
+	for prefix, fns := range c.watchers {
+		for range fns {
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	for key, fns := range c.keyWatchers {
+		for range fns {
+			prefixes = append(prefixes, key)
+		}
+	}
+	for w := range c.chanWatchers {
+		prefixes = append(prefixes, w.prefix)
+	}
+	sort.Strings(prefixes)
+	return prefixes
+}
+
+// OnChangeCount returns the number of OnChange callbacks with default defaultConfiguration.
+func OnChangeCount() int {
+	return defaultConfiguration.OnChangeCount()
+}
+
+// OnChangeCount returns the number of callbacks registered by OnChange and OnChangeNamed.
+// A callback replaced by OnChangeNamed is counted once.
+func (c *Configuration) OnChangeCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.onChanges)
+}
+
+// WatchChan delivers the changed keys under a prefix with default defaultConfiguration.
+func WatchChan(ctx context.Context, prefix string) <-chan []string {
+	return defaultConfiguration.WatchChan(ctx, prefix)
+}
+
+// WatchChan returns a channel receiving the sorted flattened keys under prefix changed by each update,
+// e.g. for a select loop. An empty prefix matches every key. Unlike Watch, `log.level` doesn't match
+// `log.levels`. Updates are queued while the receiver lags behind, none is dropped.
+// The channel is closed, and the watcher unregistered, once ctx is done.
+func (c *Configuration) WatchChan(ctx context.Context, prefix string) <-chan []string {
+	w := &chanWatcher{prefix: prefix, signal: make(chan struct{}, 1)}
+	c.mu.Lock()
+	if c.chanWatchers == nil {
+		c.chanWatchers = make(map[*chanWatcher]struct{})
+	}
+	c.chanWatchers[w] = struct{}{}
+	c.mu.Unlock()
+
+	out := make(chan []string)
+	go func() {
+		defer close(out)
+		defer func() {
+			c.mu.Lock()
+			delete(c.chanWatchers, w)
+			c.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.signal:
+			}
+			for _, keys := range w.drain() {
+				select {
+				case out <- keys:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+	return out
+}
+
+// chanWatcher queues the changed keys under prefix for WatchChan.
+type chanWatcher struct {
+	prefix string
+	// signal has a pending element whenever pending may be non-empty
+	signal  chan struct{}
+	mu      sync.Mutex
+	pending [][]string
+}
+
+// notify queues the keys of changes under the prefix of w, if any, without blocking.
+func (w *chanWatcher) notify(changes map[string]interface{}, sep string) {
+	var keys []string
+	for key := range changes {
+		if w.prefix == "" || key == w.prefix || strings.HasPrefix(key, w.prefix+sep) {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+	w.mu.Lock()
+	w.pending = append(w.pending, keys)
+	w.mu.Unlock()
+	select {
+	case w.signal <- struct{}{}:
+	default:
+	}
+}
+
+// drain returns and clears the queued keys.
+func (w *chanWatcher) drain() [][]string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	pending := w.pending
+	w.pending = nil
+	return pending
+}
+
+// WatchLogLevel follows `log.level` with default defaultConfiguration.
+func WatchLogLevel(fn func(level string)) {
+	defaultConfiguration.WatchLogLevel(fn)
+}
+
+// WatchLogLevel calls fn with the current `log.level`, then again with the new level whenever it changes.
+func (c *Configuration) WatchLogLevel(fn func(level string)) {
+	fn(c.GetString(logLevelKey))
+	c.WatchKey(logLevelKey, func(c *Configuration) {
+		fn(c.GetString(logLevelKey))
+	})
+}
+
+// Observe decodes the subtree at key into T whenever a key under it changes,
+// and calls fn with the previous and the new value if they differ by reflect.DeepEqual.
+// The initial value is decoded when registering; a failed decoding yields the zero T.
+func Observe[T any](c *Configuration, key string, fn func(old, new T)) {
+	var (
+		mu  sync.Mutex
+		old T
+	)
+	_ = c.UnmarshalKey(key, &old)
+	c.Watch(key, func(c *Configuration) {
+		var value T
+		if err := c.UnmarshalKey(key, &value); err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if reflect.DeepEqual(old, value) {
+			return
+		}
+		prev := old
+		old = value
+		fn(prev, value)
+	})
+}
@@ -0,0 +1,186 @@
+package econf
+
+import (
+	"context"
+	"encoding/json"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWatch(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("log.level", "info"))
+	assert.NoError(t, v.Set("log.levels", "all"))
+
+	prefix := make(chan struct{}, 4)
+	exact := make(chan struct{}, 4)
+	v.Watch("log.level", func(*Configuration) { prefix <- struct{}{} })
+	v.WatchKey("log.level", func(*Configuration) { exact <- struct{}{} })
+
+	assert.NoError(t, v.Set("log.levels", "none"))
+	<-prefix
+	select {
+	case <-exact:
+		t.Fatal("WatchKey fired for another key")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, v.Set("log.level", "warn"))
+	<-prefix
+	<-exact
+}
+
+func TestWatchLogLevel(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("log.level", "info"))
+
+	levels := make(chan string, 4)
+	v.WatchLogLevel(func(level string) {
+		levels <- level
+	})
+	assert.Equal(t, "info", <-levels)
+
+	assert.NoError(t, v.Set("log.level", "debug"))
+	assert.Equal(t, "debug", <-levels)
+
+	assert.NoError(t, v.Set("log.level", "error"))
+	assert.Equal(t, "error", <-levels)
+}
+
+func TestObserve(t *testing.T) {
+	type server struct {
+		Host string
+		TLS  struct {
+			Enabled bool
+		}
+	}
+	content := []byte(`{"server": {"host": "example.com", "tls": {"enabled": false}}, "servers": 1}`)
+	v := New()
+	assert.NoError(t, v.Load(content, json.Unmarshal))
+
+	type change struct{ old, new server }
+	changes := make(chan change, 4)
+	Observe(v, "server", func(old, new server) {
+		changes <- change{old: old, new: new}
+	})
+
+	// no-op reload and a false positive of the prefix don't fire
+	assert.NoError(t, v.Load(content, json.Unmarshal))
+	assert.NoError(t, v.Set("servers", float64(2)))
+	select {
+	case <-changes:
+		t.Fatal("Observe fired without change")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	assert.NoError(t, v.Load([]byte(`{"server": {"tls": {"enabled": true}}}`), json.Unmarshal))
+	got := <-changes
+	assert.Equal(t, "example.com", got.old.Host)
+	assert.False(t, got.old.TLS.Enabled)
+	assert.Equal(t, "example.com", got.new.Host)
+	assert.True(t, got.new.TLS.Enabled)
+}
+
+func TestWatchChan(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.port", 80))
+	assert.NoError(t, v.Set("server.host", "localhost"))
+	assert.NoError(t, v.Set("servers", "all"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := v.WatchChan(ctx, "server")
+
+	assert.NoError(t, v.Set("servers", "none"))
+	assert.NoError(t, v.Set("server", map[string]interface{}{"port": 81, "host": "example.com"}))
+	select {
+	case keys := <-ch:
+		assert.Equal(t, []string{"server.host", "server.port"}, keys)
+	case <-time.After(time.Second):
+		t.Fatal("no change received")
+	}
+
+	cancel()
+	select {
+	case _, ok := <-ch:
+		assert.False(t, ok)
+	case <-time.After(time.Second):
+		t.Fatal("channel not closed")
+	}
+	v.mu.RLock()
+	assert.Empty(t, v.chanWatchers)
+	v.mu.RUnlock()
+}
+
+func TestWatchDispatchBounded(t *testing.T) {
+	v := New()
+	assert.NoError(t, v.Set("server.port", 0))
+
+	const (
+		watchers = 10
+		reloads  = 200
+	)
+	gate := make(chan struct{})
+	var mu sync.Mutex
+	seen := make([][]int, watchers)
+	for w := 0; w < watchers; w++ {
+		w := w
+		v.Watch("server", func(c *Configuration) {
+			<-gate
+			mu.Lock()
+			seen[w] = append(seen[w], len(seen[w]))
+			mu.Unlock()
+		})
+	}
+
+	before := runtime.NumGoroutine()
+	for i := 1; i <= reloads; i++ {
+		assert.NoError(t, v.Set("server.port", i))
+	}
+	// the blocked callbacks are queued rather than each holding a goroutine
+	assert.LessOrEqual(t, runtime.NumGoroutine()-before, 1)
+
+	close(gate)
+	assert.Eventually(t, func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, s := range seen {
+			if len(s) != reloads {
+				return false
+			}
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+	// the dispatcher stops once idle, the condition itself runs on a goroutine of Eventually
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= before+1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherPrefixes(t *testing.T) {
+	v := New()
+	assert.Empty(t, v.WatcherPrefixes())
+	assert.Zero(t, v.OnChangeCount())
+
+	fn := func(*Configuration) {}
+	v.Watch("server", fn)
+	v.Watch("server", fn)
+	v.Watch("log", fn)
+	v.WatchKey("log.level", fn)
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := v.WatchChan(ctx, "db")
+	assert.Equal(t, []string{"db", "log", "log.level", "server", "server"}, v.WatcherPrefixes())
+
+	cancel()
+	for range ch {
+	}
+	assert.Equal(t, []string{"log", "log.level", "server", "server"}, v.WatcherPrefixes())
+
+	v.OnChange(fn)
+	v.OnChangeNamed("reload", 1, fn)
+	v.OnChangeNamed("reload", 2, fn)
+	assert.Equal(t, 2, v.OnChangeCount())
+}